@@ -0,0 +1,181 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"go-crawler/crawler"
+)
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:8080", "监听地址，默认只接受本机的worker；其他机器的worker连接时改为 :8080 并设置 -token")
+	token := flag.String("token", os.Getenv("COORDINATOR_TOKEN"), "共享令牌，worker 用相同的 -token 连接，默认取环境变量 COORDINATOR_TOKEN")
+	category := flag.String("type", "installer", "目录类型："+strings.Join(crawler.CategoryNames(), " / "))
+	country := flag.String("country", "", "国家：ENF上的英文名（如 United States）、ISO代码或中文名，后两种需要国家目录")
+	catalogPath := flag.String("countries", crawler.DefaultCatalogPath, "国家目录文件（见 cmd/countries），不存在时 -country 只能用英文名")
+	maxPages := flag.Int("maxPages", 0, "最多抓取的列表页数，0 表示直到空页")
+	output := flag.String("out", "", "输出CSV，默认写入本次运行目录中的 <type>_<country>_Pipeline<日期>.csv")
+	jsonl := flag.Bool("jsonl", false, "同时输出完整记录（候选邮箱、来源、结果代码）的 JSON Lines，与CSV同名")
+	parquetOut := flag.Bool("parquet", false, "同时输出完整记录的 Parquet，与CSV同名")
+	dbPath := flag.String("db", crawler.DefaultStorePath, "结果同时写入的数据库，为空时不写")
+	outRoot := flag.String("outDir", "runs", "输出根目录，每次运行写入其中的 <时间>-<运行ID> 子目录，latest 指向最新一次")
+	leaseTTL := flag.Duration("leaseTTL", 2*time.Minute, "租约有效期，worker超过该时间没有续租时任务重新分配")
+	batch := flag.Int("batch", 20, "worker每次最多领取的任务数")
+	retry := flag.Bool("retry", true, "官网超时或代理失败的记录在第一轮结束后重新分配一次")
+	grace := flag.Duration("grace", 15*time.Second, "收到Ctrl-C后等待worker交回结果的时间")
+	flag.Parse()
+
+	if *country == "" {
+		log.Fatal("请用 -country 指定国家")
+	}
+	if *token == "" && !loopback(*addr) {
+		log.Fatal("监听非本机地址时请用 -token 或环境变量 COORDINATOR_TOKEN 设置共享令牌")
+	}
+	cat, err := crawler.LookupCategory(*category)
+	if err != nil {
+		log.Fatal(err)
+	}
+	*category = cat.Name
+	catalog, err := crawler.LoadCatalog(*catalogPath)
+	if err != nil && !os.IsNotExist(err) {
+		log.Fatal(err)
+	}
+	name, known := crawler.ResolveCountry(catalog, *country)
+	if catalog != nil && !known {
+		log.Printf("国家目录中没有 %s，按ENF英文名处理\n", *country)
+	}
+	*country = name
+	// 数据库和监听地址都准备好再创建运行目录，否则出错时 latest 会指向一个空目录
+	var store *crawler.Store
+	if *dbPath != "" {
+		if store, err = crawler.OpenStore(*dbPath); err != nil {
+			log.Fatal(err)
+		}
+		defer store.Close()
+	}
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	run, err := crawler.NewRun(*outRoot)
+	if err != nil {
+		log.Fatal(err)
+	}
+	run.SetFlags(flag.CommandLine)
+	if *output == "" {
+		*output = run.OutputPath(crawler.InputFile{Type: *category, Country: *country}, "Pipeline")
+	}
+
+	sink, err := crawler.NewCSVSink(*output, cat.ColumnNames()...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	tally := &crawler.TallySink{}
+	sinks := []crawler.Sink{sink, tally}
+	prov := crawler.Provenance{RunID: run.ID, Source: crawler.DirectoryURL(*category, *country, 1)}
+	recordSinks, outputs, err := crawler.NewRecordSinks(*output, prov, *jsonl, *parquetOut)
+	if err != nil {
+		sink.Abort()
+		log.Fatal(err)
+	}
+	sinks = append(sinks, recordSinks...)
+	outputs = append([]string{*output}, outputs...)
+	if store != nil {
+		sinks = append(sinks, crawler.StoreSink{Store: store})
+	}
+	co := crawler.NewCoordinator(sinks...)
+	co.LeaseTTL = *leaseTTL
+	co.MaxBatch = *batch
+	co.Retry = *retry
+	co.Token = *token
+
+	go http.Serve(ln, co)
+	go co.Reap(time.Second)
+
+	shutdown := crawler.NotifyShutdown(*grace)
+	defer shutdown.Stop()
+
+	// 列表页由协调器读取，请求很少；详情页和官网交给worker
+	p := &crawler.Pipeline{
+		Category:      *category,
+		Country:       *country,
+		MaxPages:      *maxPages,
+		ENF:           crawler.NewFetcher(crawler.DefaultENFConfig()),
+		DetailWorkers: 1,
+		PageDelay:     100 * time.Millisecond,
+		Stop:          shutdown.Stopping(),
+	}
+
+	start := time.Now()
+	fmt.Printf("\n==== 协调器 %s 开始抓取 %s/%s ====\n", ln.Addr(), *category, *country)
+	listed := 0
+	for c := range p.Listing(shutdown.Context()) {
+		co.Add(c)
+		listed++
+	}
+	co.CloseInput()
+	log.Printf("列表读取完成，共 %d 家公司，等待worker处理\n", listed)
+
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+wait:
+	for {
+		select {
+		case <-co.Done():
+			break wait
+		case <-shutdown.Context().Done():
+			break wait
+		case <-ticker.C:
+			queued, leased := co.Stats()
+			log.Printf("等待中 %d 条，处理中 %d 条\n", queued, leased)
+		}
+	}
+
+	count, err := co.Close()
+	if err != nil {
+		log.Printf("写出结果失败：%v\n", err)
+	}
+	if shutdown.Interrupted() {
+		for i, path := range outputs {
+			partial := crawler.IncompletePath(path)
+			if err := os.Rename(path, partial); err != nil {
+				log.Printf("重命名未完成文件失败：%v\n", err)
+			} else {
+				outputs[i] = partial
+			}
+		}
+		*output = outputs[0]
+		fmt.Println("处理被中断，输出不完整")
+	}
+	stats := tally.Stats()
+	stats.Input = prov.Source
+	stats.Output, stats.Outputs = outputs[0], outputs[1:]
+	if err := run.AddFile(stats); err != nil {
+		log.Println(err)
+	}
+	if err := run.Finish(shutdown.Interrupted()); err != nil {
+		log.Println(err)
+	}
+	fmt.Printf("完成：共 %d 条，结果已保存到 %s\n", count, *output)
+	fmt.Printf("总耗时：%v\n", time.Since(start))
+}
+
+// loopback 监听地址是否只接受本机连接
+func loopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
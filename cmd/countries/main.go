@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"go-crawler/crawler"
+)
+
+// 从ENF各目录的首页抓取国家列表和每个国家的公司数，写入国家目录文件（默认 countries.json），
+// 取代手工维护的 United%20States 之类的国家列表。每个国家记录ENF上的英文名、ISO代码和中文名；
+// pipeline / coordinator 的 -country 可以用其中任意一种写法。
+// -list 时只打印已有的国家目录，不抓取。
+func main() {
+	types := flag.String("type", "installer,seller", "要抓取的目录，逗号分隔，可选："+strings.Join(crawler.CategoryNames(), " / "))
+	output := flag.String("out", crawler.DefaultCatalogPath, "国家目录文件，已有时只更新抓取的目录")
+	list := flag.Bool("list", false, "只打印已有的国家目录")
+	flag.Parse()
+
+	catalog, err := crawler.LoadCatalog(*output)
+	switch {
+	case os.IsNotExist(err):
+		catalog = &crawler.Catalog{}
+	case err != nil:
+		log.Fatal(err)
+	}
+	if *list {
+		printCatalog(catalog)
+		return
+	}
+
+	f := crawler.NewFetcher(crawler.DefaultENFConfig())
+	ctx := context.Background()
+	fetched := 0
+	for _, name := range crawler.SplitList(*types) {
+		cat, err := crawler.LookupCategory(name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		countries, err := crawler.FetchCountries(ctx, f, cat)
+		if err != nil {
+			log.Printf("抓取 %s 目录首页失败：%v\n", cat.Name, err)
+			continue
+		}
+		if len(countries) == 0 {
+			log.Printf("%s 目录首页上没有找到国家，页面可能已改版\n", cat.Name)
+			continue
+		}
+		catalog.Merge(cat.Name, countries)
+		fetched++
+		total := 0
+		for _, c := range countries {
+			total += c.Counts[cat.Name]
+			if c.ISO == "" {
+				log.Printf("%s 没有对应的ISO代码，请补充 crawler.isoCodes\n", c.Name)
+			}
+		}
+		fmt.Printf("%s：%d 个国家，共 %d 家公司\n", cat.Name, len(countries), total)
+	}
+	if fetched == 0 {
+		log.Fatal("没有抓取到任何目录，国家目录未更新")
+	}
+	catalog.Updated = time.Now().UTC()
+	if err := catalog.Save(*output); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("已保存：%s（%d 个国家）\n", *output, len(catalog.Countries))
+}
+
+func printCatalog(catalog *crawler.Catalog) {
+	var categories []string
+	seen := make(map[string]bool)
+	for _, c := range catalog.Countries {
+		for category := range c.Counts {
+			if !seen[category] {
+				seen[category] = true
+				categories = append(categories, category)
+			}
+		}
+	}
+	sort.Strings(categories)
+	// 按注册顺序排列目录列，不认识的目录排在最后
+	var ordered []string
+	for _, name := range crawler.CategoryNames() {
+		if seen[name] {
+			ordered = append(ordered, name)
+		}
+	}
+	for _, name := range categories {
+		if _, err := crawler.LookupCategory(name); err != nil {
+			ordered = append(ordered, name)
+		}
+	}
+
+	fmt.Printf("%-4s %-30s %-12s", "ISO", "Country", "Local")
+	for _, name := range ordered {
+		fmt.Printf(" %10s", name)
+	}
+	fmt.Println()
+	for _, c := range catalog.Countries {
+		fmt.Printf("%-4s %-30s %-12s", c.ISO, c.Name, c.Local)
+		for _, name := range ordered {
+			fmt.Printf(" %10d", c.Counts[name])
+		}
+		fmt.Println()
+	}
+	if !catalog.Updated.IsZero() {
+		fmt.Printf("共 %d 个国家，更新于 %s\n", len(catalog.Countries), catalog.Updated.Local().Format("2006-01-02 15:04"))
+	}
+}
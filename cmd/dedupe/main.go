@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-crawler/crawler"
+)
+
+// 跨目录、跨国家去重：同一家公司常常同时出现在 seller 和 installer 目录、出现在多个国家，
+// 按 ENF ID、官网域名、邮箱和公司名把记录合并为主公司（规则见 crawler.Dedupe），避免重复联系。
+// 数据来自合并后的CSV（默认 merged 目录，见 cmd/merge），指定 -db 时来自数据库。
+// 输出 Masters<日期>.csv（每家主公司一行，列出所在目录和国家）和
+// Memberships<日期>.csv（每条原始记录属于哪家主公司）。
+func main() {
+	fileType := flag.String("type", "", "只处理该类型，逗号分隔，为空时不限")
+	countries := flag.String("country", "", "只处理这些国家，逗号分隔")
+	stage := flag.String("stage", "Merged", "输入文件的阶段，如 Merged / Procedure2 / Pipeline")
+	latest := flag.Bool("latest", true, "同一国家有多个日期的文件时只用最新的")
+	dbPath := flag.String("db", "", "从数据库读取公司，不读CSV文件")
+	outDir := flag.String("outDir", "dedupe", "输出目录")
+	jsonl := flag.Bool("jsonl", false, "同时输出带全部记录的主公司 JSON Lines")
+	flag.Parse()
+	filter := crawler.InputFilter{
+		Types:     crawler.SplitList(*fileType),
+		Countries: crawler.SplitList(*countries),
+		Stage:     *stage,
+		Latest:    *latest,
+	}
+
+	var companies []crawler.Company
+	var err error
+	if *dbPath != "" {
+		companies, err = storeCompanies(*dbPath, filter)
+	} else {
+		// 输入文件：目录、通配符或文件路径，默认 merged 目录
+		patterns := flag.Args()
+		if len(patterns) == 0 {
+			patterns = []string{"merged"}
+		}
+		companies, err = fileCompanies(patterns, filter)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(companies) == 0 {
+		log.Fatal("没有符合条件的数据")
+	}
+
+	masters := crawler.Dedupe(companies)
+	date := time.Now().Format("20060102")
+	mastersPath := filepath.Join(*outDir, "Masters"+date+".csv")
+	membersPath := filepath.Join(*outDir, "Memberships"+date+".csv")
+	if err := writeMasters(mastersPath, masters); err != nil {
+		log.Fatal(err)
+	}
+	if err := writeMemberships(membersPath, masters); err != nil {
+		log.Fatal(err)
+	}
+	if *jsonl {
+		if err := writeJSONL(filepath.Join(*outDir, "Masters"+date+".jsonl"), masters); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	multi, crossCategory, crossCountry := 0, 0, 0
+	for _, m := range masters {
+		if len(m.Members) > 1 {
+			multi++
+		}
+		if len(m.Categories) > 1 {
+			crossCategory++
+		}
+		if len(m.Countries) > 1 {
+			crossCountry++
+		}
+	}
+	fmt.Printf("%d 条记录 → %d 家公司，其中 %d 家有多条记录（跨目录 %d 家，跨国家 %d 家）\n",
+		len(companies), len(masters), multi, crossCategory, crossCountry)
+	fmt.Printf("已保存：%s、%s\n", mastersPath, membersPath)
+}
+
+func fileCompanies(patterns []string, filter crawler.InputFilter) ([]crawler.Company, error) {
+	inputs, err := crawler.DiscoverInputs(patterns, filter)
+	if err != nil {
+		return nil, err
+	}
+	var companies []crawler.Company
+	for _, in := range inputs {
+		rows, _, skipped, err := crawler.LoadCompanies(in.Path, crawler.FieldNumber, crawler.FieldName)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", in.Path, err)
+		}
+		for _, e := range skipped {
+			log.Printf("%s：跳过 %v\n", in.Path, e)
+		}
+		for i := range rows {
+			rows[i].Category, rows[i].Country = in.Type, in.Country
+		}
+		companies = append(companies, rows...)
+	}
+	return companies, nil
+}
+
+func storeCompanies(path string, filter crawler.InputFilter) ([]crawler.Company, error) {
+	store, err := crawler.OpenStore(path)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+	groups, err := store.Groups(filter)
+	if err != nil {
+		return nil, err
+	}
+	var companies []crawler.Company
+	for _, g := range groups {
+		rows, err := store.Companies(g.Category, g.Country)
+		if err != nil {
+			return nil, err
+		}
+		companies = append(companies, rows...)
+	}
+	return companies, nil
+}
+
+func writeMasters(path string, masters []crawler.Master) error {
+	return writeCSV(path, []string{
+		"Master ID", crawler.FieldName, crawler.FieldLink2, crawler.FieldEmail, crawler.FieldGuessed,
+		"All Emails", "Directories", "Countries", "ENF IDs", "Matched By", "Records",
+	}, func(w *csv.Writer) {
+		for _, m := range masters {
+			w.Write([]string{
+				m.ID, m.Name, m.Website, m.Email, m.GuessedEmail,
+				strings.Join(m.Emails, "; "), strings.Join(m.Categories, "; "), strings.Join(m.Countries, "; "),
+				strings.Join(m.ENFIDs, "; "), strings.Join(m.MatchedBy, "; "), strconv.Itoa(len(m.Members)),
+			})
+		}
+	})
+}
+
+func writeMemberships(path string, masters []crawler.Master) error {
+	return writeCSV(path, []string{
+		"Master ID", "Directory", crawler.FieldCountry, crawler.FieldNumber, crawler.FieldName, "Key",
+	}, func(w *csv.Writer) {
+		for _, m := range masters {
+			for _, r := range m.Members {
+				w.Write([]string{m.ID, r.Category, r.Country, strconv.Itoa(r.Number), r.Name, r.Key})
+			}
+		}
+	})
+}
+
+func writeCSV(path string, header []string, rows func(*csv.Writer)) error {
+	f, err := crawler.CreateAtomic(path)
+	if err != nil {
+		return fmt.Errorf("无法创建 %s: %v", path, err)
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	w.Write(header)
+	rows(w)
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("写入 %s 失败: %v", path, err)
+	}
+	return f.Commit()
+}
+
+func writeJSONL(path string, masters []crawler.Master) error {
+	f, err := crawler.CreateAtomic(path)
+	if err != nil {
+		return fmt.Errorf("无法创建 %s: %v", path, err)
+	}
+	defer f.Close()
+	enc := json.NewEncoder(f)
+	enc.SetEscapeHTML(false)
+	for _, m := range masters {
+		if err := enc.Encode(m); err != nil {
+			return fmt.Errorf("写入 %s 失败: %v", path, err)
+		}
+	}
+	if err := f.Commit(); err != nil {
+		return err
+	}
+	fmt.Printf("已保存：%s\n", path)
+	return nil
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+
+	"go-crawler/crawler"
+)
+
+// 导出 XLSX（替代 procedure3.py）、CSV、JSON Lines 或 Parquet。数据来自 Procedure2（或流水线输出）CSV，
+// 指定 -db 时来自数据库。默认每个类型、国家一个文件；-combined 时写入同一个文件，
+// XLSX 每个国家一个工作表。
+func main() {
+	fileType := flag.String("type", "", "只导出该类型，逗号分隔，为空时不限")
+	countries := flag.String("country", "", "只导出这些国家，逗号分隔")
+	stage := flag.String("stage", "Procedure2", "输入文件的阶段，如 Procedure2 / Pipeline")
+	latest := flag.Bool("latest", true, "同一国家有多个日期的文件时只导出最新的")
+	companyDir := flag.String("company", "Company", "Company 目录，记录中没有官网时按 Number 从这里补上，为空时不补")
+	dbPath := flag.String("db", "", "从数据库导出，不读CSV文件")
+	format := flag.String("format", "xlsx", "导出格式：xlsx / csv / jsonl / parquet")
+	outDir := flag.String("outDir", "procedure3", "输出目录")
+	combined := flag.Bool("combined", false, "写入同一个文件，XLSX 每个国家一个工作表")
+	output := flag.String("out", "", "-combined 时的输出文件，默认 <outDir>/Export<日期>.<格式>")
+	flag.Parse()
+	switch *format {
+	case "xlsx", "csv", "jsonl", "parquet":
+	default:
+		log.Fatalf("不支持的格式 %s", *format)
+	}
+	filter := crawler.InputFilter{
+		Types:     crawler.SplitList(*fileType),
+		Countries: crawler.SplitList(*countries),
+		Stage:     *stage,
+		Latest:    *latest,
+	}
+
+	var groups []group
+	var err error
+	if *dbPath != "" {
+		groups, err = storeGroups(*dbPath, filter)
+	} else {
+		// 输入文件：目录、通配符或文件路径，默认 procedure2 目录
+		patterns := flag.Args()
+		if len(patterns) == 0 {
+			patterns = []string{"procedure2"}
+		}
+		groups, err = fileGroups(patterns, filter, *companyDir)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(groups) == 0 {
+		log.Fatal("没有符合条件的数据")
+	}
+
+	if *combined {
+		if *output == "" {
+			*output = filepath.Join(*outDir, fmt.Sprintf("Export%s.%s", time.Now().Format("20060102"), *format))
+		}
+		if *format == "xlsx" {
+			err = exportCombined(*output, groups)
+		} else {
+			err = exportRecords(*output, *format, groups...)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("已保存：%s\n", *output)
+		return
+	}
+	for _, g := range groups {
+		customerType := crawler.CustomerType(g.category)
+		var path string
+		if *format == "xlsx" {
+			path = filepath.Join(*outDir, fmt.Sprintf("%s_%s.xlsx", customerType, g.country))
+			err = exportWorkbook(path, customerType, g.rows)
+		} else {
+			path = filepath.Join(*outDir, crawler.OutputName(g.category, g.country, "Export", time.Now().Format("20060102"), *format))
+			err = exportRecords(path, *format, g)
+		}
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		fmt.Printf("已保存：%s（%d 条）\n", path, len(g.rows))
+	}
+}
+
+// group 一个类型、国家的记录
+type group struct {
+	category, country string
+	source            string // 输入文件或数据库，记入 JSONL / Parquet 的来源
+	rows              []crawler.Company
+}
+
+func storeGroups(path string, filter crawler.InputFilter) ([]group, error) {
+	store, err := crawler.OpenStore(path)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+	list, err := store.Groups(filter)
+	if err != nil {
+		return nil, err
+	}
+	var groups []group
+	for _, g := range list {
+		rows, err := store.Companies(g.Category, g.Country)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, group{g.Category, g.Country, path, rows})
+	}
+	return groups, nil
+}
+
+func fileGroups(patterns []string, filter crawler.InputFilter, companyDir string) ([]group, error) {
+	inputs, err := crawler.DiscoverInputs(patterns, filter)
+	if err != nil {
+		return nil, err
+	}
+	var groups []group
+	for _, in := range inputs {
+		rows, err := loadRows(in, companyDir)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		groups = append(groups, group{in.Type, in.Country, in.Path, rows})
+	}
+	return groups, nil
+}
+
+func exportCombined(path string, groups []group) error {
+	types := make(map[string]bool)
+	for _, g := range groups {
+		types[g.category] = true
+	}
+	book, err := crawler.NewWorkbook()
+	if err != nil {
+		return err
+	}
+	defer book.Close()
+	for _, g := range groups {
+		// 只有一种类型时工作表名就是国家名
+		sheet := g.country
+		if len(types) > 1 {
+			sheet = crawler.CustomerType(g.category) + " " + g.country
+		}
+		if err := book.AddSheet(sheet, g.rows); err != nil {
+			return fmt.Errorf("%s: %v", sheet, err)
+		}
+		fmt.Printf("%s：%d 条\n", sheet, len(g.rows))
+	}
+	return book.Save(path)
+}
+
+// exportRecords CSV 按流水线输出的列（CSVHeader 加上目录特有的列）写出，
+// JSONL / Parquet 写出完整记录（crawler.Record）
+func exportRecords(path, format string, groups ...group) error {
+	var sink crawler.Sink
+	var err error
+	switch format {
+	case "csv":
+		var details []string
+		seen := make(map[string]bool)
+		for _, g := range groups {
+			if cat, err := crawler.LookupCategory(g.category); err == nil {
+				for _, name := range cat.ColumnNames() {
+					if !seen[name] {
+						seen[name] = true
+						details = append(details, name)
+					}
+				}
+			}
+		}
+		sink, err = crawler.NewCSVSink(path, details...)
+	case "jsonl":
+		sink, err = crawler.NewJSONLSink(path, crawler.Provenance{})
+	case "parquet":
+		sink, err = crawler.NewParquetSink(path, crawler.Provenance{})
+	}
+	if err != nil {
+		return err
+	}
+	for _, g := range groups {
+		// 合并导出时各组来自不同的输入
+		if s, ok := sink.(interface{ SetSource(string) }); ok {
+			s.SetSource(g.source)
+		}
+		for _, c := range g.rows {
+			if err := sink.Write(c); err != nil {
+				crawler.AbortSinks(sink)
+				return err
+			}
+		}
+	}
+	return sink.Close()
+}
+
+func exportWorkbook(path, sheet string, rows []crawler.Company) error {
+	book, err := crawler.NewWorkbook()
+	if err != nil {
+		return err
+	}
+	defer book.Close()
+	if err := book.AddSheet(sheet, rows); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	return book.Save(path)
+}
+
+// loadRows 读取一个输入文件，补上国家、类型，以及 Company 文件中同一 Number 的官网
+func loadRows(in crawler.InputFile, companyDir string) ([]crawler.Company, error) {
+	rows, _, skipped, err := crawler.LoadCompanies(in.Path, crawler.FieldNumber, crawler.FieldName)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range skipped {
+		log.Printf("%s：跳过 %v\n", in.Path, e)
+	}
+	var websites map[int]string
+	for i := range rows {
+		c := &rows[i]
+		if c.Country == "" {
+			c.Country = in.Country
+		}
+		if c.Category == "" {
+			c.Category = in.Type
+		}
+		if c.Link2 != "" || companyDir == "" {
+			continue
+		}
+		if websites == nil {
+			if websites, err = crawler.CompanyWebsites(in, companyDir); err != nil {
+				log.Printf("%s：%v，官网列留空\n", in.Path, err)
+				websites = map[int]string{}
+			}
+		}
+		c.Link2 = websites[c.Number]
+	}
+	return rows, nil
+}
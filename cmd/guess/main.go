@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strconv"
+
+	"go-crawler/crawler"
+)
+
+// 替代 procedure2.py：Procedure1 中没有邮箱的记录按官网域名猜测邮箱。
+// 猜测的邮箱写在单独的 Guessed Email 列，Email 列只保留抓取到的邮箱。
+// 指定 -db 时从数据库读取公司，猜测结果作为候选邮箱写回数据库，不读写CSV。
+func main() {
+	fileType := flag.String("type", "", "只处理该类型的文件，逗号分隔，为空时不限")
+	countries := flag.String("country", "", "只处理这些国家，逗号分隔")
+	latest := flag.Bool("latest", false, "同一国家有多个日期的文件时只处理最新的")
+	companyDir := flag.String("company", "Company", "Company 目录，记录中没有官网时按 Number 从这里补上，为空时不补")
+	outDir := flag.String("outDir", "procedure2", "输出目录")
+	dbPath := flag.String("db", "", "从数据库读取公司并把猜测写回数据库")
+	flag.Parse()
+	filter := crawler.InputFilter{
+		Types:     crawler.SplitList(*fileType),
+		Countries: crawler.SplitList(*countries),
+		Stage:     "Procedure1",
+		Latest:    *latest,
+	}
+	if *dbPath != "" {
+		if err := guessStore(*dbPath, filter); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// 输入文件：目录、通配符或文件路径，默认 procedure1 目录
+	patterns := flag.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"procedure1"}
+	}
+	inputs, err := crawler.DiscoverInputs(patterns, filter)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(inputs) == 0 {
+		log.Fatal("没有符合条件的输入文件")
+	}
+	for _, in := range inputs {
+		output := filepath.Join(*outDir, crawler.OutputName(in.Type, in.Country, "Procedure2", in.Date, "csv"))
+		if err := guessFile(in, *companyDir, output); err != nil {
+			log.Printf("%s：%v\n", in.Path, err)
+		}
+	}
+}
+
+func guessFile(in crawler.InputFile, companyDir, output string) error {
+	rows, _, skipped, err := crawler.LoadCompanies(in.Path, crawler.FieldNumber, crawler.FieldName)
+	if err != nil {
+		return err
+	}
+	for _, e := range skipped {
+		log.Printf("%s：跳过 %v\n", in.Path, e)
+	}
+
+	out, err := crawler.CreateAtomic(output)
+	if err != nil {
+		return fmt.Errorf("无法创建 %s: %v", output, err)
+	}
+	defer out.Close()
+	writer := csv.NewWriter(out)
+	writer.Write([]string{crawler.FieldNumber, crawler.FieldName, crawler.FieldEmail, crawler.FieldLink2, crawler.FieldGuessed})
+
+	var websites map[int]string
+	scraped, guessed, empty := 0, 0, 0
+	for _, c := range rows {
+		if c.Link2 == "" && companyDir != "" {
+			if websites == nil {
+				if websites, err = crawler.CompanyWebsites(in, companyDir); err != nil {
+					log.Printf("%s：%v，没有官网的记录不猜测\n", in.Path, err)
+					websites = map[int]string{}
+				}
+			}
+			c.Link2 = websites[c.Number]
+		}
+		switch {
+		case c.Email != "":
+			scraped++
+		default:
+			if c.GuessedEmail == "" {
+				c.GuessedEmail = crawler.GuessEmail(c.Link2, in.Country)
+			}
+			if c.GuessedEmail != "" {
+				guessed++
+			} else {
+				empty++
+			}
+		}
+		writer.Write([]string{strconv.Itoa(c.Number), c.Name, c.Email, c.Link2, c.GuessedEmail})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("写入 %s 失败: %v", output, err)
+	}
+	if err := out.Commit(); err != nil {
+		return err
+	}
+	fmt.Printf("%s：抓取到邮箱 %d 条，猜测 %d 条，无法猜测 %d 条\n", filepath.Base(output), scraped, guessed, empty)
+	return nil
+}
+
+// guessStore 数据库中没有抓取到邮箱的公司按官网（跳转后的）域名猜测
+func guessStore(path string, filter crawler.InputFilter) error {
+	store, err := crawler.OpenStore(path)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	groups, err := store.Groups(filter)
+	if err != nil {
+		return err
+	}
+	for _, g := range groups {
+		companies, err := store.Companies(g.Category, g.Country)
+		if err != nil {
+			return err
+		}
+		var guessed []crawler.Company
+		empty := 0
+		for _, c := range companies {
+			if c.Email != "" {
+				continue
+			}
+			website := c.FinalURL
+			if website == "" {
+				website = c.Link2
+			}
+			email := crawler.GuessEmail(website, c.Country)
+			if email == "" {
+				empty++
+				continue
+			}
+			guessed = append(guessed, crawler.Company{
+				Category:     c.Category,
+				Country:      c.Country,
+				Number:       c.Number,
+				Link1:        c.Link1,
+				GuessedEmail: email,
+			})
+		}
+		if err := store.Save(guessed...); err != nil {
+			return err
+		}
+		fmt.Printf("%s %s：共 %d 家，猜测 %d 条，无法猜测 %d 条\n", g.Category, g.Country, len(companies), len(guessed), empty)
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"go-crawler/crawler"
+)
+
+// 按稳定的公司标识（ENF ID / 详情页路径，没有时用 Number 加公司名）把 Company、Procedure1、
+// Procedure2 等阶段文件合并成一个文件，替代 procedure3.py 按行号拼接官网的做法。
+// 匹配规则和冲突时的取值顺序见 crawler.MergeStages。
+// 每个类型、国家输出 <type>_<country>_Merged<日期>.csv，以及列出 orphan / missing / duplicate /
+// conflict 的 <type>_<country>_MergeReport<日期>.csv。
+func main() {
+	fileType := flag.String("type", "", "只合并该类型，逗号分隔，为空时不限")
+	countries := flag.String("country", "", "只合并这些国家，逗号分隔")
+	latest := flag.Bool("latest", true, "同一国家同一阶段有多个日期的文件时只用最新的")
+	companyDir := flag.String("company", "Company", "Company 目录")
+	outDir := flag.String("outDir", "merged", "输出目录")
+	jsonl := flag.Bool("jsonl", false, "同时输出完整记录（含候选邮箱）的 JSON Lines")
+	flag.Parse()
+
+	// 阶段文件：目录、通配符或文件路径，默认 procedure1 和 procedure2 目录
+	patterns := flag.Args()
+	if len(patterns) == 0 {
+		for _, dir := range []string{"procedure1", "procedure2"} {
+			if _, err := os.Stat(dir); err == nil {
+				patterns = append(patterns, dir)
+			}
+		}
+	}
+	inputs, err := crawler.DiscoverInputs(patterns, crawler.InputFilter{
+		Types:     crawler.SplitList(*fileType),
+		Countries: crawler.SplitList(*countries),
+		Latest:    *latest,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// 按类型、国家分组，DiscoverInputs 的结果已按类型、国家排序
+	var groups [][]crawler.InputFile
+	for _, in := range inputs {
+		if in.Stage == "Company" {
+			continue
+		}
+		if n := len(groups); n > 0 && groups[n-1][0].Type == in.Type && groups[n-1][0].Country == in.Country {
+			groups[n-1] = append(groups[n-1], in)
+		} else {
+			groups = append(groups, []crawler.InputFile{in})
+		}
+	}
+	if len(groups) == 0 {
+		log.Fatal("没有符合条件的阶段文件")
+	}
+	for _, files := range groups {
+		if err := mergeGroup(files, *companyDir, *outDir, *jsonl); err != nil {
+			log.Printf("%s %s：%v\n", files[0].Type, files[0].Country, err)
+		}
+	}
+}
+
+func mergeGroup(files []crawler.InputFile, companyDir, outDir string, jsonl bool) error {
+	first := files[0]
+	companyFile, err := crawler.FindCompanyFile(first, companyDir)
+	if err != nil {
+		return err
+	}
+	companies, err := load(companyFile, crawler.FieldNumber, crawler.FieldName)
+	if err != nil {
+		return err
+	}
+	var stages []crawler.StageRows
+	for _, in := range files {
+		rows, err := load(in, crawler.FieldNumber, crawler.FieldName)
+		if err != nil {
+			return err
+		}
+		stages = append(stages, crawler.StageRows{Stage: in.Stage, Path: in.Path, Rows: rows})
+	}
+	merged, issues := crawler.MergeStages(companies, stages...)
+
+	output := filepath.Join(outDir, crawler.OutputName(first.Type, first.Country, "Merged", companyFile.Date, "csv"))
+	sinks := []crawler.Sink{}
+	csvSink, err := crawler.NewCSVSink(output)
+	if err != nil {
+		return err
+	}
+	sinks = append(sinks, csvSink)
+	if jsonl {
+		s, err := crawler.NewJSONLSink(strings.TrimSuffix(output, ".csv")+".jsonl", crawler.Provenance{Source: companyFile.Path})
+		if err != nil {
+			csvSink.Abort()
+			return err
+		}
+		sinks = append(sinks, s)
+	}
+	for _, c := range merged {
+		for _, s := range sinks {
+			if err := s.Write(c); err != nil {
+				log.Println(err)
+			}
+		}
+	}
+	for _, s := range sinks {
+		if err := s.Close(); err != nil {
+			return err
+		}
+	}
+	report := filepath.Join(outDir, crawler.OutputName(first.Type, first.Country, "MergeReport", companyFile.Date, "csv"))
+	if err := writeReport(report, issues); err != nil {
+		return err
+	}
+
+	counts := make(map[string]int)
+	for _, issue := range issues {
+		counts[issue.Kind]++
+	}
+	var used []string
+	for _, in := range files {
+		used = append(used, filepath.Base(in.Path))
+	}
+	fmt.Printf("%s + %s → %s：%d 家公司；orphan %d，missing %d，duplicate %d，conflict %d，详见 %s\n",
+		filepath.Base(companyFile.Path), strings.Join(used, " + "), output, len(merged),
+		counts[crawler.IssueOrphan], counts[crawler.IssueMissing], counts[crawler.IssueDuplicate], counts[crawler.IssueConflict], report)
+	return nil
+}
+
+func load(in crawler.InputFile, required ...string) ([]crawler.Company, error) {
+	rows, _, skipped, err := crawler.LoadCompanies(in.Path, required...)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range skipped {
+		log.Printf("%s：跳过 %v\n", in.Path, e)
+	}
+	for i := range rows {
+		rows[i].Category, rows[i].Country = in.Type, in.Country
+	}
+	return rows, nil
+}
+
+func writeReport(path string, issues []crawler.MergeIssue) error {
+	f, err := crawler.CreateAtomic(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	w.Write([]string{"Kind", "Stage", crawler.FieldNumber, crawler.FieldName, "Key", "Field", "Kept", "Dropped"})
+	for _, issue := range issues {
+		w.Write([]string{issue.Kind, issue.Stage, strconv.Itoa(issue.Number), issue.Name, issue.Key, issue.Field, issue.Kept, issue.Dropped})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	return f.Commit()
+}
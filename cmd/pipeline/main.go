@@ -0,0 +1,165 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"go-crawler/crawler"
+)
+
+func main() {
+	category := flag.String("type", "installer", "目录类型："+strings.Join(crawler.CategoryNames(), " / "))
+	country := flag.String("country", "", "国家：ENF上的英文名（如 United States）、ISO代码或中文名，后两种需要国家目录")
+	catalogPath := flag.String("countries", crawler.DefaultCatalogPath, "国家目录文件（见 cmd/countries），不存在时 -country 只能用英文名")
+	maxPages := flag.Int("maxPages", 0, "最多抓取的列表页数，0 表示直到空页")
+	detailWorkers := flag.Int("detailWorkers", 5, "ENF详情页并发数")
+	websiteWorkers := flag.Int("websiteWorkers", 50, "公司官网并发数（开启 -adaptive 时为上限）")
+	perDomain := flag.Int("perDomain", 2, "同一注册域名同时抓取的官网数，0 表示不限制")
+	adaptive := flag.Bool("adaptive", true, "根据超时和429自动调整官网并发")
+	retry := flag.Bool("retry", true, "官网超时或代理失败的记录在最后用更长超时、其他代理和链接变体再试一次")
+	output := flag.String("out", "", "输出CSV，默认写入本次运行目录中的 <type>_<country>_Pipeline<日期>.csv")
+	jsonl := flag.Bool("jsonl", false, "同时输出完整记录（候选邮箱、来源、结果代码）的 JSON Lines，与CSV同名")
+	parquetOut := flag.Bool("parquet", false, "同时输出完整记录的 Parquet，与CSV同名")
+	dbPath := flag.String("db", crawler.DefaultStorePath, "结果同时写入的数据库，为空时不写")
+	outRoot := flag.String("outDir", "runs", "输出根目录，每次运行写入其中的 <时间>-<运行ID> 子目录，latest 指向最新一次")
+	robots := flag.Bool("robots", false, "抓取公司官网时遵守robots.txt")
+	cacheDir := flag.String("cache", "cache", "响应缓存目录，为空时不缓存")
+	cacheTTL := flag.Duration("cacheTTL", 7*24*time.Hour, "缓存有效期，过期后重新下载")
+	offline := flag.Bool("offline", false, "离线模式：公司官网只从缓存读取，不访问网络；ENF列表页和详情页仍在线抓取")
+	grace := flag.Duration("grace", 15*time.Second, "收到Ctrl-C后等待进行中请求的时间")
+	tlsAllow := flag.String("tlsAllow", "tls_allow.txt", "证书白名单文件，每行一个host")
+	flag.Parse()
+
+	if *country == "" {
+		log.Fatal("请用 -country 指定国家")
+	}
+	cat, err := crawler.LookupCategory(*category)
+	if err != nil {
+		log.Fatal(err)
+	}
+	*category = cat.Name
+	catalog, err := crawler.LoadCatalog(*catalogPath)
+	if err != nil && !os.IsNotExist(err) {
+		log.Fatal(err)
+	}
+	name, known := crawler.ResolveCountry(catalog, *country)
+	if catalog != nil && !known {
+		log.Printf("国家目录中没有 %s，按ENF英文名处理\n", *country)
+	}
+	*country = name
+	// 参数和配置都检查完再创建运行目录，否则出错时 latest 会指向一个空目录
+	policy, err := crawler.LoadTLSPolicy(*tlsAllow)
+	if err != nil {
+		log.Fatal(err)
+	}
+	enfCfg := crawler.DefaultENFConfig()
+	siteCfg := crawler.DefaultFetchConfig()
+	siteCfg.TLS = policy
+	siteCfg.Robots = *robots
+	if *adaptive {
+		siteCfg.Limiter = crawler.NewAdaptiveLimiter(min(10, *websiteWorkers), *websiteWorkers)
+	}
+	siteCfg.CacheTTL = *cacheTTL
+	siteCfg.Offline = *offline
+	if *cacheDir != "" {
+		cache, err := crawler.OpenCache(*cacheDir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		siteCfg.Cache = cache
+	} else if *offline {
+		log.Fatal("离线模式需要指定 -cache 目录")
+	}
+
+	var retryFetcher *crawler.Fetcher
+	if *retry {
+		retryFetcher = crawler.NewFetcher(crawler.RetryConfig(siteCfg))
+	}
+
+	var store *crawler.Store
+	if *dbPath != "" {
+		if store, err = crawler.OpenStore(*dbPath); err != nil {
+			log.Fatal(err)
+		}
+		defer store.Close()
+	}
+
+	run, err := crawler.NewRun(*outRoot)
+	if err != nil {
+		log.Fatal(err)
+	}
+	run.SetFlags(flag.CommandLine)
+	if *output == "" {
+		*output = run.OutputPath(crawler.InputFile{Type: *category, Country: *country}, "Pipeline")
+	}
+
+	sink, err := crawler.NewCSVSink(*output, cat.ColumnNames()...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	tally := &crawler.TallySink{}
+	sinks := []crawler.Sink{sink, tally}
+	prov := crawler.Provenance{RunID: run.ID, Source: crawler.DirectoryURL(*category, *country, 1)}
+	recordSinks, outputs, err := crawler.NewRecordSinks(*output, prov, *jsonl, *parquetOut)
+	if err != nil {
+		sink.Abort()
+		log.Fatal(err)
+	}
+	sinks = append(sinks, recordSinks...)
+	outputs = append([]string{*output}, outputs...)
+	if store != nil {
+		sinks = append(sinks, crawler.StoreSink{Store: store})
+	}
+
+	shutdown := crawler.NotifyShutdown(*grace)
+	defer shutdown.Stop()
+
+	p := &crawler.Pipeline{
+		Category:       *category,
+		Country:        *country,
+		MaxPages:       *maxPages,
+		ENF:            crawler.NewFetcher(enfCfg),
+		Website:        crawler.NewFetcher(siteCfg),
+		Retry:          retryFetcher,
+		DetailWorkers:  *detailWorkers,
+		WebsiteWorkers: *websiteWorkers,
+		PerDomain:      *perDomain,
+		PageDelay:      100 * time.Millisecond,
+		Sinks:          sinks,
+		Stop:           shutdown.Stopping(),
+	}
+
+	start := time.Now()
+	fmt.Printf("\n==== 开始抓取 %s/%s ====\n", *category, *country)
+	count, err := p.Run(shutdown.Context())
+	if err != nil {
+		log.Printf("写出结果失败：%v\n", err)
+	}
+	if shutdown.Interrupted() {
+		for i, path := range outputs {
+			partial := crawler.IncompletePath(path)
+			if err := os.Rename(path, partial); err != nil {
+				log.Printf("重命名未完成文件失败：%v\n", err)
+			} else {
+				outputs[i] = partial
+			}
+		}
+		*output = outputs[0]
+		fmt.Println("处理被中断，输出不完整")
+	}
+	stats := tally.Stats()
+	stats.Input = prov.Source
+	stats.Output, stats.Outputs = outputs[0], outputs[1:]
+	if err := run.AddFile(stats); err != nil {
+		log.Println(err)
+	}
+	if err := run.Finish(shutdown.Interrupted()); err != nil {
+		log.Println(err)
+	}
+	fmt.Printf("完成：共 %d 条，结果已保存到 %s\n", count, *output)
+	fmt.Printf("总耗时：%v\n", time.Since(start))
+}
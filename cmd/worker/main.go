@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"go-crawler/crawler"
+)
+
+func main() {
+	coordinator := flag.String("coordinator", "http://127.0.0.1:8080", "协调器地址")
+	token := flag.String("token", os.Getenv("COORDINATOR_TOKEN"), "与协调器相同的共享令牌，默认取环境变量 COORDINATOR_TOKEN")
+	id := flag.String("id", "", "worker名称，默认 <主机名>-<进程号>")
+	workers := flag.Int("workers", 20, "同时处理的任务数（开启 -adaptive 时为官网并发上限）")
+	batch := flag.Int("batch", 10, "每次领取的任务数")
+	heartbeat := flag.Duration("heartbeat", 30*time.Second, "续租间隔，应小于协调器的 -leaseTTL")
+	adaptive := flag.Bool("adaptive", true, "根据超时和429自动调整官网并发")
+	robots := flag.Bool("robots", false, "抓取公司官网时遵守robots.txt")
+	cacheDir := flag.String("cache", "cache", "响应缓存目录，为空时不缓存")
+	grace := flag.Duration("grace", 15*time.Second, "收到Ctrl-C后等待进行中请求的时间")
+	tlsAllow := flag.String("tlsAllow", "tls_allow.txt", "证书白名单文件，每行一个host")
+	flag.Parse()
+
+	if *id == "" {
+		host, _ := os.Hostname()
+		*id = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+
+	policy, err := crawler.LoadTLSPolicy(*tlsAllow)
+	if err != nil {
+		log.Fatal(err)
+	}
+	siteCfg := crawler.DefaultFetchConfig()
+	siteCfg.TLS = policy
+	siteCfg.Robots = *robots
+	if *adaptive {
+		siteCfg.Limiter = crawler.NewAdaptiveLimiter(min(10, *workers), *workers)
+	}
+	if *cacheDir != "" {
+		cache, err := crawler.OpenCache(*cacheDir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		siteCfg.Cache = cache
+		siteCfg.CacheTTL = 7 * 24 * time.Hour
+	}
+
+	shutdown := crawler.NotifyShutdown(*grace)
+	defer shutdown.Stop()
+
+	p := &crawler.Pipeline{
+		ENF:     crawler.NewFetcher(crawler.DefaultENFConfig()),
+		Website: crawler.NewFetcher(siteCfg),
+		Retry:   crawler.NewFetcher(crawler.RetryConfig(siteCfg)),
+	}
+	w := crawler.NewWorker(*coordinator, *id, p)
+	w.Token = *token
+	w.Workers = *workers
+	w.Batch = *batch
+	w.Heartbeat = *heartbeat
+	w.Stop = shutdown.Stopping()
+
+	fmt.Printf("\n==== worker %s 连接协调器 %s ====\n", *id, *coordinator)
+	if err := w.Run(shutdown.Context()); err != nil && !shutdown.Interrupted() {
+		log.Fatal(err)
+	}
+}
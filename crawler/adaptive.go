@@ -0,0 +1,121 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// AdaptiveLimiter 按AIMD调整并发：每个统计窗口内失败率和平均耗时正常时并发+1，
+// 超时或429增多时乘以 Backoff 降低。-maxConcurrency 只作为上限。
+type AdaptiveLimiter struct {
+	Min           int
+	Max           int
+	Window        int           // 每多少个请求调整一次
+	MaxErrorRate  float64       // 窗口内超时/429比例超过该值即降低并发
+	TargetLatency time.Duration // 窗口内平均耗时超过该值即降低并发
+	Backoff       float64       // 降低时的乘数
+
+	mu       sync.Mutex
+	limit    float64
+	inFlight int
+	wake     chan struct{}
+
+	samples   int
+	overloads int
+	latency   time.Duration
+}
+
+// NewAdaptiveLimiter 从 initial 开始，在 [1, max] 之间调整
+func NewAdaptiveLimiter(initial, max int) *AdaptiveLimiter {
+	if max < 1 {
+		max = 1
+	}
+	if initial < 1 || initial > max {
+		initial = max
+	}
+	return &AdaptiveLimiter{
+		Min:           1,
+		Max:           max,
+		Window:        20,
+		MaxErrorRate:  0.2,
+		TargetLatency: 3 * time.Second,
+		Backoff:       0.7,
+		limit:         float64(initial),
+		wake:          make(chan struct{}),
+	}
+}
+
+// Limit 当前并发上限
+func (l *AdaptiveLimiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.limit)
+}
+
+// Acquire 占用一个名额，达到当前上限时等待
+func (l *AdaptiveLimiter) Acquire(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		if l.inFlight < int(l.limit) {
+			l.inFlight++
+			l.mu.Unlock()
+			return nil
+		}
+		wake := l.wake
+		l.mu.Unlock()
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Release 释放名额并记录本次请求的耗时及是否过载
+func (l *AdaptiveLimiter) Release(latency time.Duration, overloaded bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inFlight--
+	l.samples++
+	l.latency += latency
+	if overloaded {
+		l.overloads++
+	}
+	if l.samples >= l.Window {
+		l.adjust()
+	}
+	close(l.wake)
+	l.wake = make(chan struct{})
+}
+
+func (l *AdaptiveLimiter) adjust() {
+	old := int(l.limit)
+	errorRate := float64(l.overloads) / float64(l.samples)
+	avg := l.latency / time.Duration(l.samples)
+	if errorRate > l.MaxErrorRate || avg > l.TargetLatency {
+		l.limit *= l.Backoff
+		if l.limit < float64(l.Min) {
+			l.limit = float64(l.Min)
+		}
+	} else if l.limit < float64(l.Max) {
+		l.limit++
+	}
+	if int(l.limit) != old {
+		log.Printf("并发调整：%d → %d（超时/429比例 %.0f%%，平均耗时 %v）\n", old, int(l.limit), errorRate*100, avg.Round(time.Millisecond))
+	}
+	l.samples, l.overloads, l.latency = 0, 0, 0
+}
+
+// isOverload 判断一次请求是否说明对端或网络已经过载：超时或429/503
+func isOverload(resp *http.Response, err error) bool {
+	if err != nil {
+		var netErr net.Error
+		return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout())
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable
+}
@@ -0,0 +1,121 @@
+package crawler
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// AtomicFile 先写入同目录下的临时文件 .<name>.tmp-*，Commit 时 fsync 后改名为目标文件。
+// 写到一半崩溃或磁盘写满时目标文件保持原样，临时文件留在目录中可以查看。
+type AtomicFile struct {
+	*os.File
+	path string
+	done bool
+}
+
+// CreateAtomic 在 path 所在目录创建临时文件
+func CreateAtomic(path string) (*AtomicFile, error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return nil, err
+	}
+	// CreateTemp 建出的文件只有本用户可读
+	if err := f.Chmod(0644); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return &AtomicFile{File: f, path: path}, nil
+}
+
+// Commit fsync 后改名为目标文件
+func (f *AtomicFile) Commit() error {
+	return f.CommitAs(f.path)
+}
+
+// CommitAs fsync 后改名为 path（同一目录），如中断时改为 _INCOMPLETE 文件名
+func (f *AtomicFile) CommitAs(path string) error {
+	if f.done {
+		return nil
+	}
+	f.done = true
+	if err := f.Sync(); err != nil {
+		f.File.Close()
+		os.Remove(f.Name())
+		return fmt.Errorf("写入 %s 失败: %v", path, err)
+	}
+	if err := f.File.Close(); err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("写入 %s 失败: %v", path, err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("写入 %s 失败: %v", path, err)
+	}
+	syncDir(filepath.Dir(path))
+	return nil
+}
+
+// Close 放弃写入并删除临时文件；已经 Commit 时什么也不做，可以用 defer 调用
+func (f *AtomicFile) Close() error {
+	if f.done {
+		return nil
+	}
+	f.done = true
+	f.File.Close()
+	return os.Remove(f.Name())
+}
+
+// syncDir 让改名落盘；部分系统（如Windows）不能 fsync 目录，忽略错误
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
+
+// writeFileAtomic 一次写入整个文件，并发写同一个文件时不会读到半个文件
+func writeFileAtomic(path string, data []byte) error {
+	f, err := CreateAtomic(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+	return f.Commit()
+}
+
+// BackupFile 把 path 备份为同目录下的 <name>.<时间>.bak，返回备份路径。
+// 优先用硬链接，之后原子替换 path 不会影响备份；不支持硬链接时复制。
+func BackupFile(path string) (string, error) {
+	backup := fmt.Sprintf("%s.%s.bak", path, time.Now().Format("20060102-150405"))
+	if err := os.Link(path, backup); err == nil {
+		return backup, nil
+	}
+	src, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("备份 %s 失败: %v", path, err)
+	}
+	defer src.Close()
+	dst, err := CreateAtomic(backup)
+	if err != nil {
+		return "", fmt.Errorf("备份 %s 失败: %v", path, err)
+	}
+	defer dst.Close()
+	if _, err := io.Copy(dst, src); err != nil {
+		return "", fmt.Errorf("备份 %s 失败: %v", path, err)
+	}
+	if err := dst.Commit(); err != nil {
+		return "", err
+	}
+	return backup, nil
+}
@@ -0,0 +1,121 @@
+package crawler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var ErrCacheMiss = errors.New("离线模式下缓存未命中")
+
+// cacheEntry 索引文件内容，正文按内容哈希单独存放
+type cacheEntry struct {
+	URL        string      `json:"url"`
+	FinalURL   string      `json:"final_url"`
+	StatusCode int         `json:"status"`
+	Header     http.Header `json:"header"`
+	BodyHash   string      `json:"body_sha256"`
+	FetchedAt  time.Time   `json:"fetched_at"`
+	UsedProxy  bool        `json:"used_proxy,omitempty"`
+	Insecure   bool        `json:"insecure,omitempty"`
+	TLSError   string      `json:"tls_error,omitempty"`
+}
+
+// Cache 磁盘响应缓存。
+// index/ 下按URL哈希存放响应信息，blobs/ 下按正文内容哈希存放解压后的正文，
+// 相同内容的页面只存一份。
+type Cache struct {
+	dir string
+}
+
+// OpenCache 打开（必要时创建）缓存目录
+func OpenCache(dir string) (*Cache, error) {
+	for _, sub := range []string{"index", "blobs"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			return nil, fmt.Errorf("创建缓存目录失败: %v", err)
+		}
+	}
+	return &Cache{dir: dir}, nil
+}
+
+func hashHex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// 按哈希前两位分子目录，避免单个目录文件过多
+func (c *Cache) path(kind, hash string) string {
+	return filepath.Join(c.dir, kind, hash[:2], hash)
+}
+
+// Cacheable 只缓存成功的响应和确定不存在的页面；403、429、5xx 多半是临时封锁，
+// 缓存下来会在有效期内（以及离线模式下）一直重放这次失败
+func Cacheable(status int) bool {
+	return status >= 200 && status < 300 || status == http.StatusNotFound || status == http.StatusGone
+}
+
+// Get 读取缓存，maxAge<=0 表示不检查过期时间
+func (c *Cache) Get(target string, maxAge time.Duration) (*Response, bool) {
+	data, err := os.ReadFile(c.path("index", hashHex([]byte(target))))
+	if err != nil {
+		return nil, false
+	}
+	var e cacheEntry
+	if err := json.Unmarshal(data, &e); err != nil || e.URL != target || !Cacheable(e.StatusCode) {
+		return nil, false
+	}
+	if maxAge > 0 && time.Since(e.FetchedAt) > maxAge {
+		return nil, false
+	}
+	body, err := os.ReadFile(c.path("blobs", e.BodyHash))
+	if err != nil {
+		return nil, false
+	}
+	return &Response{
+		URL:        e.URL,
+		FinalURL:   e.FinalURL,
+		StatusCode: e.StatusCode,
+		Header:     e.Header,
+		Body:       body,
+		UsedProxy:  e.UsedProxy,
+		Insecure:   e.Insecure,
+		TLSError:   e.TLSError,
+		FetchedAt:  e.FetchedAt,
+		FromCache:  true,
+	}, true
+}
+
+// Put 写入缓存，不可缓存的状态码（见 Cacheable）直接忽略
+func (c *Cache) Put(resp *Response) error {
+	if !Cacheable(resp.StatusCode) {
+		return nil
+	}
+	bodyHash := hashHex(resp.Body)
+	blob := c.path("blobs", bodyHash)
+	if _, err := os.Stat(blob); err != nil {
+		if err := writeFileAtomic(blob, resp.Body); err != nil {
+			return err
+		}
+	}
+	data, err := json.Marshal(cacheEntry{
+		URL:        resp.URL,
+		FinalURL:   resp.FinalURL,
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		BodyHash:   bodyHash,
+		FetchedAt:  resp.FetchedAt,
+		UsedProxy:  resp.UsedProxy,
+		Insecure:   resp.Insecure,
+		TLSError:   resp.TLSError,
+	})
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(c.path("index", hashHex([]byte(resp.URL))), data)
+}
@@ -0,0 +1,195 @@
+package crawler
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Column 目录列表页上的一列
+type Column struct {
+	Name    string   // CSV中的列名，也是 Company.Details 的键
+	Aliases []string // 列表页表头的写法，小写，比较前表头也转小写、去掉冒号并合并空白
+}
+
+// Category 一个ENF目录：列表页地址、解析方式和特有的列
+type Category struct {
+	Name         string   // 命令行和文件名中的类型，只能是字母，如 installer
+	Path         string   // 列表页路径 /directory/<Path>/<国家>
+	CustomerType string   // 导出表中的客户类型
+	Columns      []Column // 公司名、地址以外的列，解析到 Company.Details，CSV中排在流水线列之后
+	Parse        func(body []byte, cat Category, country string) ([]ListingEntry, error)
+}
+
+// ColumnNames 特有列的列名
+func (c Category) ColumnNames() []string {
+	names := make([]string, len(c.Columns))
+	for i, col := range c.Columns {
+		names[i] = col.Name
+	}
+	return names
+}
+
+// 已知的目录。installer、seller 的列表页是同一种表格；组件、逆变器等厂商目录的表格带表头，
+// 各列按表头对应，页面改版时调整这里的别名即可。
+var categories = []Category{
+	{Name: "installer", Path: "installer", CustomerType: "Installers", Parse: parseCompanyTable},
+	{Name: "seller", Path: "seller", CustomerType: "Sellers", Parse: parseCompanyTable},
+	{Name: "panel", Path: "panel", CustomerType: "Panel Manufacturers", Parse: parseProductTable, Columns: []Column{
+		{"Panel Type", []string{"panel type", "type", "cell type", "组件类型", "电池片类型", "类型"}},
+		{"Power Range", []string{"power range", "power", "output", "功率范围", "功率"}},
+		{"Headquarters", []string{"headquarters", "hq", "总部"}},
+	}},
+	{Name: "inverter", Path: "inverter", CustomerType: "Inverter Manufacturers", Parse: parseProductTable, Columns: []Column{
+		{"Inverter Type", []string{"inverter type", "type", "逆变器类型", "类型"}},
+		{"Power Range", []string{"power range", "power", "rated power", "功率范围", "功率"}},
+		{"Headquarters", []string{"headquarters", "hq", "总部"}},
+	}},
+	{Name: "storage", Path: "storage", CustomerType: "Storage Manufacturers", Parse: parseProductTable, Columns: []Column{
+		{"Battery Type", []string{"battery type", "chemistry", "type", "电池类型", "类型"}},
+		{"Capacity", []string{"capacity", "storage capacity", "energy", "容量", "储能容量"}},
+		{"Application", []string{"application", "applications", "应用", "应用场景"}},
+		{"Headquarters", []string{"headquarters", "hq", "总部"}},
+	}},
+	{Name: "mounting", Path: "mounting-system", CustomerType: "Mounting System Manufacturers", Parse: parseProductTable, Columns: []Column{
+		{"Mounting Type", []string{"mounting type", "system type", "type", "安装方式", "支架类型", "类型"}},
+		{"Material", []string{"material", "材料"}},
+		{"Headquarters", []string{"headquarters", "hq", "总部"}},
+	}},
+	{Name: "component", Path: "component", CustomerType: "Component Manufacturers", Parse: parseProductTable, Columns: []Column{
+		{"Component Type", []string{"component type", "product type", "products", "type", "部件类型", "产品类型", "类型"}},
+		{"Headquarters", []string{"headquarters", "hq", "总部"}},
+	}},
+}
+
+// 目录名的其它写法
+var categoryAliases = map[string]string{
+	"installers": "installer", "sellers": "seller", "distributor": "seller",
+	"panels": "panel", "module": "panel", "inverters": "inverter",
+	"battery": "storage", "batteries": "storage", "mountings": "mounting", "components": "component",
+}
+
+// LookupCategory 按名称查找目录，不区分大小写
+func LookupCategory(name string) (Category, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if alias, ok := categoryAliases[name]; ok {
+		name = alias
+	}
+	for _, c := range categories {
+		if c.Name == name {
+			return c, nil
+		}
+	}
+	return Category{}, fmt.Errorf("不支持的目录类型 %q，可选：%s", name, strings.Join(CategoryNames(), " / "))
+}
+
+// CategoryNames 已知目录的名称
+func CategoryNames() []string {
+	names := make([]string, len(categories))
+	for i, c := range categories {
+		names[i] = c.Name
+	}
+	return names
+}
+
+// RegisterCategory 增加或替换一个目录
+func RegisterCategory(c Category) {
+	for i := range categories {
+		if categories[i].Name == c.Name {
+			categories[i] = c
+			return
+		}
+	}
+	categories = append(categories, c)
+}
+
+// detailColumns 所有目录的特有列，读取CSV时这些列进入 Company.Details
+func detailColumns() map[string]string {
+	m := make(map[string]string)
+	for _, c := range categories {
+		for _, col := range c.Columns {
+			m[normalizeHeader(col.Name)] = col.Name
+		}
+	}
+	return m
+}
+
+// parseCompanyTable installer、seller 列表页：每家公司一行 tr.mkjs-el，
+// 地址在 td.no-left-right-padding，公司链接带 data-event（见 DataEvent）
+func parseCompanyTable(body []byte, cat Category, country string) ([]ListingEntry, error) {
+	return ParseListing(body, DataEvent(cat.Name, country))
+}
+
+// parseProductTable 厂商目录列表页：带表头的表格，公司链接在第一个指向详情页的 a 中，
+// 其余各列按表头与 cat.Columns 的别名对应；对应不上的列忽略
+func parseProductTable(body []byte, cat Category, country string) ([]ListingEntry, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("解析列表页失败: %v", err)
+	}
+	dataEvent := DataEvent(cat.Name, country)
+	var entries []ListingEntry
+	doc.Find("table").Each(func(_ int, table *goquery.Selection) {
+		// 表头：列位置 → 列名
+		columns := make(map[int]string)
+		addressCol := -1
+		header := table.Find("thead tr").First()
+		if header.Length() == 0 {
+			header = table.Find("tr").First()
+		}
+		if header.ChildrenFiltered("th").Length() == 0 {
+			return // 没有表头的表格不是列表
+		}
+		header.ChildrenFiltered("th, td").Each(func(i int, th *goquery.Selection) {
+			label := profileLabel(th.Text())
+			if label == "address" || label == "地址" || label == "location" || label == "所在地" {
+				addressCol = i
+				return
+			}
+			for _, col := range cat.Columns {
+				for _, alias := range col.Aliases {
+					if label == alias {
+						if _, used := columns[i]; !used {
+							columns[i] = col.Name
+						}
+					}
+				}
+			}
+		})
+		table.Find("tr").Each(func(_ int, row *goquery.Selection) {
+			if row.ChildrenFiltered("td").Length() == 0 {
+				return // 表头
+			}
+			cells := row.ChildrenFiltered("th, td") // 与表头的列位置一致
+			link := row.Find(fmt.Sprintf(`a[data-event="%s"]`, dataEvent)).First()
+			if link.Length() == 0 {
+				link = cells.First().Find("a[href]").First()
+			}
+			name := cleanText(link.Text())
+			link1, _ := link.Attr("href")
+			if name == "" || link1 == "" {
+				return // 广告或特殊行
+			}
+			e := ListingEntry{Name: name, Link1: ENFURL(link1)}
+			cells.Each(func(i int, td *goquery.Selection) {
+				text := strings.Join(listItems(td), ", ")
+				switch {
+				case i == addressCol:
+					e.Address = cleanText(td.Text())
+				case columns[i] != "" && text != "":
+					if e.Details == nil {
+						e.Details = make(map[string]string)
+					}
+					e.Details[columns[i]] = text
+				}
+			})
+			if e.Address == "" {
+				e.Address = cleanText(row.Find("td.no-left-right-padding").First().Text())
+			}
+			entries = append(entries, e)
+		})
+	})
+	return entries, nil
+}
@@ -0,0 +1,71 @@
+package crawler
+
+import (
+	"strconv"
+	"strings"
+)
+
+// 邮箱来源
+const (
+	EmailFromProfile = "profile" // ENF详情页
+	EmailFromWebsite = "website" // 公司官网
+	EmailGuessed     = "guess"   // 按官网域名猜测，只出现在 GuessedEmail 和数据库的候选邮箱中
+)
+
+// Company 在各阶段之间流转的公司记录
+type Company struct {
+	Category    string // 目录，见 CategoryNames
+	Country     string
+	Number      int
+	Name        string
+	Address     string
+	Link1       string // ENF详情页
+	Link2       string // 公司官网
+	Email       string
+	EmailSource string // 见 EmailFrom*
+	FinalURL    string // 官网跳转后的地址，猜测邮箱时用它的域名
+	// GuessedEmail 按官网域名猜测的邮箱，未经验证；只在没有抓取到邮箱时填写，从不写入 Email
+	GuessedEmail string
+	Candidates   []EmailCandidate  // 各阶段找到或猜测的全部邮箱，Email / GuessedEmail 是从中选出的
+	TLS          string            // 官网证书校验失败的类别
+	Outcome      string            // 官网抓取结果代码
+	Pass         string            // 结果由第几轮产生，见 PassMain / PassRetry
+	Details      map[string]string // 目录特有的列，如组件的功率范围，见 Category.Columns
+}
+
+// EmailCandidate 一个候选邮箱及其来源
+type EmailCandidate struct {
+	Email  string `json:"email" parquet:"email"`
+	Source string `json:"source" parquet:"source"` // 见 EmailFrom* / EmailGuessed
+}
+
+// AddCandidates 追加候选邮箱，同一来源的同一邮箱只记一次
+func (c *Company) AddCandidates(source string, emails ...string) {
+	for _, email := range emails {
+		if email == "" {
+			continue
+		}
+		dup := false
+		for _, cand := range c.Candidates {
+			if cand.Source == source && strings.EqualFold(cand.Email, email) {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			c.Candidates = append(c.Candidates, EmailCandidate{Email: email, Source: source})
+		}
+	}
+}
+
+// Key 同一目录、国家内公司的稳定标识：ENF公司ID，其次详情页路径，都没有时用 Number。
+// Number 是列表中的序号，不同日期抓取的列表可能不同，只能作为后备。
+func (c Company) Key() string {
+	if id := ENFID(c.Link1); id != "" {
+		return "enf:" + id
+	}
+	if slug := ENFSlug(c.Link1); slug != "" {
+		return "slug:" + slug
+	}
+	return "n:" + strconv.Itoa(c.Number)
+}
@@ -0,0 +1,390 @@
+package crawler
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 分布式模式的HTTP接口
+const (
+	PathLease     = "/lease"     // 领取任务
+	PathHeartbeat = "/heartbeat" // 续租
+	PathComplete  = "/complete"  // 提交结果
+)
+
+// LeaseRequest worker领取任务的请求
+type LeaseRequest struct {
+	Worker string `json:"worker"`
+	Max    int    `json:"max"`
+}
+
+// Lease 一次领取的一批任务，到期前没有续租或提交就重新分配给其他worker
+type Lease struct {
+	ID      string    `json:"id"`
+	Worker  string    `json:"worker"`
+	Jobs    []Company `json:"jobs"`
+	Expires time.Time `json:"expires"`
+}
+
+// LeaseResult worker提交的结果
+type LeaseResult struct {
+	ID      string    `json:"id"`
+	Results []Company `json:"results"`
+}
+
+// Coordinator 保存任务队列和结果，worker通过HTTP领取任务、续租、提交结果。
+// 领取接口返回204表示暂时没有任务，410表示全部完成，worker可以退出。
+type Coordinator struct {
+	LeaseTTL time.Duration // 租约有效期
+	MaxBatch int           // 每次最多领取的任务数
+	Retry    bool          // 官网请求失败的结果重新排队，按重试轮再处理一次
+	Token    string        // 共享令牌，非空时请求须带 Authorization: Bearer <Token>
+	Sinks    []Sink
+
+	order    *Reorder[Company] // 按 Add 的顺序写出
+	seqs     map[int]int       // Number → 加入顺序，Number 可以不连续
+	mu       sync.Mutex
+	queue    []Company
+	retries  []Company // 第一轮全部结束后才分配
+	leases   map[string]*Lease
+	closed   bool // 不再有新任务
+	finished bool
+	stopped  bool // Close 之后不再接受结果
+	done     chan struct{}
+	written  int
+}
+
+// NewCoordinator 创建协调器，结果按任务加入的顺序写入sinks，重试轮的结果追加在后面
+func NewCoordinator(sinks ...Sink) *Coordinator {
+	co := &Coordinator{
+		LeaseTTL: 2 * time.Minute,
+		MaxBatch: 20,
+		Retry:    true,
+		Sinks:    sinks,
+		seqs:     make(map[int]int),
+		leases:   make(map[string]*Lease),
+		done:     make(chan struct{}),
+	}
+	co.order = NewReorder(0, func(c Company) error {
+		co.written++
+		fmt.Printf("%d,%s,%s,%s,%s,%s,%s,%s\n", c.Number, c.Name, c.Link2, c.Email, c.EmailSource, c.Outcome, c.Pass, c.TLS)
+		return writeSinks(co.Sinks, c)
+	})
+	return co
+}
+
+// Add 加入一个待处理的任务，Number 在一次运行中不能重复
+func (co *Coordinator) Add(c Company) {
+	co.mu.Lock()
+	defer co.mu.Unlock()
+	co.seqs[c.Number] = len(co.seqs)
+	co.queue = append(co.queue, c)
+}
+
+// CloseInput 表示不会再有新任务，全部任务处理完后 Done 关闭
+func (co *Coordinator) CloseInput() {
+	co.mu.Lock()
+	defer co.mu.Unlock()
+	co.closed = true
+	co.checkDone()
+}
+
+// Done 全部任务都写出后关闭
+func (co *Coordinator) Done() <-chan struct{} {
+	return co.done
+}
+
+// Close 写出暂存的记录（中断时跳过空缺，还没重试完的记录——包括租给worker的——按第一轮结果写出）
+// 并关闭输出，返回写出的记录数和第一个写出错误。之后提交的结果一律拒绝。
+func (co *Coordinator) Close() (int, error) {
+	co.mu.Lock()
+	defer co.mu.Unlock()
+	if co.stopped {
+		return co.written, nil
+	}
+	co.stopped = true
+	pending := append([]Company(nil), co.retries...)
+	for _, l := range co.leases {
+		for _, c := range l.Jobs {
+			if c.Pass == PassRetry {
+				pending = append(pending, c)
+			}
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		return co.seqs[pending[i].Number] < co.seqs[pending[j].Number]
+	})
+	var sinkErr error
+	for _, c := range pending {
+		if c.Outcome == "" {
+			continue
+		}
+		c.Pass = PassMain
+		if err := co.order.Put(co.seqs[c.Number], guess(c)); err != nil && sinkErr == nil {
+			sinkErr = err
+		}
+	}
+	co.retries = nil
+	co.leases = make(map[string]*Lease)
+	if err := co.order.Flush(); err != nil && sinkErr == nil {
+		sinkErr = err
+	}
+	for _, s := range co.Sinks {
+		if err := s.Close(); err != nil && sinkErr == nil {
+			sinkErr = err
+		}
+	}
+	return co.written, sinkErr
+}
+
+// Stats 队列中、租出中的任务数
+func (co *Coordinator) Stats() (queued, leased int) {
+	co.mu.Lock()
+	defer co.mu.Unlock()
+	for _, l := range co.leases {
+		leased += len(l.Jobs)
+	}
+	return len(co.queue) + len(co.retries), leased
+}
+
+// Reap 每隔一段时间回收过期租约，直到全部完成
+func (co *Coordinator) Reap(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-co.done:
+			return
+		case now := <-ticker.C:
+			co.expire(now)
+		}
+	}
+}
+
+// expire 过期租约中的任务放回队列最前面
+func (co *Coordinator) expire(now time.Time) {
+	co.mu.Lock()
+	defer co.mu.Unlock()
+	for id, l := range co.leases {
+		if now.Before(l.Expires) {
+			continue
+		}
+		log.Printf("worker %s 的租约 %s 已过期，%d 条任务重新分配\n", l.Worker, id, len(l.Jobs))
+		delete(co.leases, id)
+		for _, c := range l.Jobs {
+			if c.Pass == PassRetry {
+				co.retries = append([]Company{c}, co.retries...)
+			} else {
+				co.queue = append([]Company{c}, co.queue...)
+			}
+		}
+	}
+}
+
+func (co *Coordinator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !co.authorized(r) {
+		http.Error(w, "令牌错误", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "只支持POST", http.StatusMethodNotAllowed)
+		return
+	}
+	switch r.URL.Path {
+	case PathLease:
+		var req LeaseRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		lease, status := co.lease(req)
+		if lease == nil {
+			w.WriteHeader(status)
+			return
+		}
+		writeJSON(w, lease)
+	case PathHeartbeat:
+		var req Lease
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		lease, ok := co.heartbeat(req.ID)
+		if !ok {
+			http.Error(w, "租约不存在或已过期", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, lease)
+	case PathComplete:
+		var res LeaseResult
+		if err := json.NewDecoder(r.Body).Decode(&res); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		status, err := co.complete(res)
+		switch {
+		case err != nil:
+			log.Printf("写出租约 %s 的结果失败：%v\n", res.ID, err)
+			http.Error(w, err.Error(), status)
+		case status == http.StatusConflict:
+			// 租约过期后任务已经交给别的worker，这份结果丢弃
+			http.Error(w, "租约不存在或已过期", status)
+		case status == http.StatusGone:
+			http.Error(w, "协调器已关闭", status)
+		default:
+			w.WriteHeader(status)
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// authorized 没有设置 Token 时不检查
+func (co *Coordinator) authorized(r *http.Request) bool {
+	if co.Token == "" {
+		return true
+	}
+	got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(got), []byte(co.Token)) == 1
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("返回响应失败：%v\n", err)
+	}
+}
+
+// lease 分配一批任务；第一轮任务全部完成后才分配重试任务
+func (co *Coordinator) lease(req LeaseRequest) (*Lease, int) {
+	co.mu.Lock()
+	defer co.mu.Unlock()
+	if co.finished || co.stopped {
+		return nil, http.StatusGone
+	}
+	n := req.Max
+	if n <= 0 || n > co.MaxBatch {
+		n = co.MaxBatch
+	}
+	queue := &co.queue
+	if len(co.queue) == 0 && co.closed && !co.mainLeased() {
+		queue = &co.retries
+	}
+	n = min(n, len(*queue))
+	if n == 0 {
+		return nil, http.StatusNoContent
+	}
+	l := &Lease{
+		ID:      newLeaseID(),
+		Worker:  req.Worker,
+		Jobs:    append([]Company(nil), (*queue)[:n]...),
+		Expires: time.Now().Add(co.LeaseTTL),
+	}
+	*queue = (*queue)[n:]
+	co.leases[l.ID] = l
+	return l, http.StatusOK
+}
+
+// mainLeased 是否还有第一轮的任务在worker手里
+func (co *Coordinator) mainLeased() bool {
+	for _, l := range co.leases {
+		for _, c := range l.Jobs {
+			if c.Pass != PassRetry {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (co *Coordinator) heartbeat(id string) (*Lease, bool) {
+	co.mu.Lock()
+	defer co.mu.Unlock()
+	l, ok := co.leases[id]
+	if !ok {
+		return nil, false
+	}
+	l.Expires = time.Now().Add(co.LeaseTTL)
+	return l, true
+}
+
+// complete 写出结果，返回HTTP状态码和写出错误；官网请求失败的第一轮结果按配置放入重试队列，
+// 并在写出顺序中让出位置
+func (co *Coordinator) complete(res LeaseResult) (int, error) {
+	co.mu.Lock()
+	defer co.mu.Unlock()
+	if co.stopped {
+		return http.StatusGone, nil
+	}
+	l, ok := co.leases[res.ID]
+	if !ok {
+		return http.StatusConflict, nil
+	}
+	delete(co.leases, res.ID)
+	// worker没有交回结果的任务（如中途退出）重新排队，不属于该租约的结果忽略
+	leased := make(map[int]bool, len(l.Jobs))
+	for _, c := range l.Jobs {
+		leased[c.Number] = true
+	}
+	returned := make(map[int]bool, len(res.Results))
+	for _, c := range res.Results {
+		if leased[c.Number] {
+			returned[c.Number] = true
+		}
+	}
+	for _, c := range l.Jobs {
+		if returned[c.Number] {
+			continue
+		}
+		if c.Pass == PassRetry {
+			co.retries = append(co.retries, c)
+		} else {
+			co.queue = append(co.queue, c)
+		}
+	}
+	var writeErr error
+	for _, c := range res.Results {
+		if !returned[c.Number] {
+			continue
+		}
+		delete(returned, c.Number) // 同一任务只写一次
+		seq := co.seqs[c.Number]
+		if co.Retry && c.Pass != PassRetry && RetryableOutcome(c.Outcome) {
+			c.Pass = PassRetry
+			co.retries = append(co.retries, c)
+			co.order.Skip(seq)
+			continue
+		}
+		if err := co.order.Put(seq, guess(c)); err != nil && writeErr == nil {
+			writeErr = err
+		}
+	}
+	co.checkDone()
+	if writeErr != nil {
+		return http.StatusInternalServerError, writeErr
+	}
+	return http.StatusNoContent, nil
+}
+
+func (co *Coordinator) checkDone() {
+	if co.finished || !co.closed || len(co.queue) > 0 || len(co.retries) > 0 || len(co.leases) > 0 {
+		return
+	}
+	co.finished = true
+	close(co.done)
+}
+
+func newLeaseID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
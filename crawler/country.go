@@ -0,0 +1,226 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// DefaultCatalogPath 国家目录文件，由 cmd/countries 从ENF目录首页生成
+const DefaultCatalogPath = "countries.json"
+
+// Country ENF目录中的一个国家
+type Country struct {
+	Name   string         `json:"name"`          // ENF上的英文名，如 United States，也是文件名和 -country 中用的名字
+	ISO    string         `json:"iso,omitempty"` // ISO 3166-1 两位代码，ENF的国家名不在 isoCodes 中时为空
+	Local  string         `json:"local,omitempty"`
+	Counts map[string]int `json:"counts"` // 目录 → 公司数
+}
+
+// Catalog 从ENF目录首页抓取的国家列表
+type Catalog struct {
+	Updated   time.Time `json:"updated"`
+	Countries []Country `json:"countries"`
+}
+
+// ENF国家名 → ISO 3166-1 两位代码。ENF的国家名与ISO的英文名不完全相同，如 Czech Republic、Taiwan。
+var isoCodes = map[string]string{
+	"Afghanistan": "AF", "Albania": "AL", "Algeria": "DZ", "Angola": "AO", "Argentina": "AR", "Armenia": "AM",
+	"Australia": "AU", "Austria": "AT", "Azerbaijan": "AZ", "Bahamas": "BS", "Bahrain": "BH", "Bangladesh": "BD",
+	"Barbados": "BB", "Belarus": "BY", "Belgium": "BE", "Belize": "BZ", "Benin": "BJ", "Bolivia": "BO",
+	"Bosnia and Herzegovina": "BA", "Botswana": "BW", "Brazil": "BR", "Bulgaria": "BG", "Burkina Faso": "BF",
+	"Burundi": "BI", "Cambodia": "KH", "Cameroon": "CM", "Canada": "CA", "Chile": "CL", "China": "CN",
+	"Colombia": "CO", "Costa Rica": "CR", "Croatia": "HR", "Cuba": "CU", "Cyprus": "CY", "Czech Republic": "CZ",
+	"Czechia": "CZ", "Democratic Republic of the Congo": "CD", "Denmark": "DK", "Dominican Republic": "DO",
+	"Ecuador": "EC", "Egypt": "EG", "El Salvador": "SV", "Estonia": "EE", "Ethiopia": "ET", "Fiji": "FJ",
+	"Finland": "FI", "France": "FR", "Gambia": "GM", "Georgia": "GE", "Germany": "DE", "Ghana": "GH", "Greece": "GR",
+	"Guatemala": "GT", "Guinea": "GN", "Haiti": "HT", "Honduras": "HN", "Hong Kong": "HK", "Hungary": "HU",
+	"Iceland": "IS", "India": "IN", "Indonesia": "ID", "Iran": "IR", "Iraq": "IQ", "Ireland": "IE", "Israel": "IL",
+	"Italy": "IT", "Ivory Coast": "CI", "Jamaica": "JM", "Japan": "JP", "Jordan": "JO", "Kazakhstan": "KZ",
+	"Kenya": "KE", "Kosovo": "XK", "Kuwait": "KW", "Kyrgyzstan": "KG", "Laos": "LA", "Latvia": "LV", "Lebanon": "LB",
+	"Lesotho": "LS", "Liberia": "LR", "Libya": "LY", "Lithuania": "LT", "Luxembourg": "LU", "Macau": "MO",
+	"Madagascar": "MG", "Malawi": "MW", "Malaysia": "MY", "Maldives": "MV", "Mali": "ML", "Malta": "MT",
+	"Mauritius": "MU", "Mexico": "MX", "Moldova": "MD", "Monaco": "MC", "Mongolia": "MN", "Montenegro": "ME",
+	"Morocco": "MA", "Mozambique": "MZ", "Myanmar": "MM", "Namibia": "NA", "Nepal": "NP", "Netherlands": "NL",
+	"New Zealand": "NZ", "Nicaragua": "NI", "Niger": "NE", "Nigeria": "NG", "North Macedonia": "MK", "Norway": "NO",
+	"Oman": "OM", "Pakistan": "PK", "Palestine": "PS", "Panama": "PA", "Papua New Guinea": "PG", "Paraguay": "PY",
+	"Peru": "PE", "Philippines": "PH", "Poland": "PL", "Portugal": "PT", "Puerto Rico": "PR", "Qatar": "QA",
+	"Romania": "RO", "Russia": "RU", "Rwanda": "RW", "Saudi Arabia": "SA", "Senegal": "SN", "Serbia": "RS",
+	"Sierra Leone": "SL", "Singapore": "SG", "Slovakia": "SK", "Slovenia": "SI", "Somalia": "SO",
+	"South Africa": "ZA", "South Korea": "KR", "Spain": "ES", "Sri Lanka": "LK", "Sudan": "SD", "Sweden": "SE",
+	"Switzerland": "CH", "Syria": "SY", "Taiwan": "TW", "Tajikistan": "TJ", "Tanzania": "TZ", "Thailand": "TH",
+	"Togo": "TG", "Trinidad and Tobago": "TT", "Tunisia": "TN", "Turkey": "TR", "Uganda": "UG", "Ukraine": "UA",
+	"United Arab Emirates": "AE", "United Kingdom": "GB", "United States": "US", "Uruguay": "UY",
+	"Uzbekistan": "UZ", "Venezuela": "VE", "Vietnam": "VN", "Yemen": "YE", "Zambia": "ZM", "Zimbabwe": "ZW",
+}
+
+// CountryISO ENF国家名对应的ISO代码，不认识时返回空
+func CountryISO(name string) string {
+	return isoCodes[CleanCountry(name)]
+}
+
+// CleanCountry 去掉URL编码（如 United%20States → United States）和多余的空白
+func CleanCountry(name string) string {
+	if decoded, err := url.PathUnescape(name); err == nil {
+		name = decoded
+	}
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// 国家链接文字中的公司数，如 德国 (6,990)、Germany（244）
+var reCountryCount = regexp.MustCompile(`[(（]\s*([\d,]+)\s*[)）]`)
+
+// ParseDirectoryIndex 解析目录首页（如 /directory/installer）上的国家链接：
+// 国家名取自链接路径，公司数取自链接或其父元素文字中括号里的数字，
+// 链接文字去掉数字后作为本地名称（中文站上是中文国家名）
+func ParseDirectoryIndex(body []byte, cat Category) ([]Country, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("解析目录首页失败: %v", err)
+	}
+	prefix := "/directory/" + cat.Path + "/"
+	seen := make(map[string]int)
+	var countries []Country
+	doc.Find("a[href]").Each(func(_ int, a *goquery.Selection) {
+		href, _ := a.Attr("href")
+		u, err := url.Parse(href)
+		if err != nil {
+			return
+		}
+		i := strings.Index(u.EscapedPath(), prefix)
+		if i < 0 {
+			return
+		}
+		rest := strings.Trim(u.EscapedPath()[i+len(prefix):], "/")
+		if rest == "" || strings.Contains(rest, "/") {
+			return // 目录本身或地区下的子页面
+		}
+		name := CleanCountry(rest)
+		text := cleanText(a.Text())
+		count := -1
+		for _, s := range []string{text, cleanText(a.Parent().Text())} {
+			if m := reCountryCount.FindStringSubmatch(s); m != nil {
+				count, _ = strconv.Atoi(strings.ReplaceAll(m[1], ",", ""))
+				break
+			}
+		}
+		local := strings.TrimSpace(reCountryCount.ReplaceAllString(text, ""))
+		if strings.EqualFold(local, name) {
+			local = ""
+		}
+		if j, ok := seen[name]; ok {
+			// 同一国家的链接可能出现多次（如导航和列表），保留有数字的那个
+			if countries[j].Counts[cat.Name] < 0 && count >= 0 {
+				countries[j].Counts[cat.Name] = count
+			}
+			if countries[j].Local == "" {
+				countries[j].Local = local
+			}
+			return
+		}
+		seen[name] = len(countries)
+		countries = append(countries, Country{
+			Name:   name,
+			ISO:    CountryISO(name),
+			Local:  local,
+			Counts: map[string]int{cat.Name: count},
+		})
+	})
+	for i := range countries {
+		if countries[i].Counts[cat.Name] < 0 {
+			countries[i].Counts[cat.Name] = 0 // 页面上没有数字
+		}
+	}
+	return countries, nil
+}
+
+// FetchCountries 抓取并解析一个目录的首页
+func FetchCountries(ctx context.Context, f *Fetcher, cat Category) ([]Country, error) {
+	resp, err := f.Fetch(ctx, fmt.Sprintf("%s/directory/%s", ENFBaseURL, cat.Path))
+	if err != nil {
+		return nil, err
+	}
+	return ParseDirectoryIndex(resp.Body, cat)
+}
+
+// LoadCatalog 读取国家目录文件
+func LoadCatalog(path string) (*Catalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Catalog
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %v", path, err)
+	}
+	return &c, nil
+}
+
+// Save 按国家名排序后原子写入
+func (c *Catalog) Save(path string) error {
+	sort.Slice(c.Countries, func(i, j int) bool { return c.Countries[i].Name < c.Countries[j].Name })
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(data, '\n'))
+}
+
+// Merge 并入一个目录的国家和公司数，已有的国家只更新该目录的数字
+func (c *Catalog) Merge(category string, countries []Country) {
+	for _, country := range countries {
+		found := false
+		for i := range c.Countries {
+			if c.Countries[i].Name != country.Name {
+				continue
+			}
+			if c.Countries[i].Counts == nil {
+				c.Countries[i].Counts = make(map[string]int)
+			}
+			c.Countries[i].Counts[category] = country.Counts[category]
+			if c.Countries[i].Local == "" {
+				c.Countries[i].Local = country.Local
+			}
+			if c.Countries[i].ISO == "" {
+				c.Countries[i].ISO = country.ISO
+			}
+			found = true
+			break
+		}
+		if !found {
+			c.Countries = append(c.Countries, country)
+		}
+	}
+}
+
+// Lookup 按ENF国家名、ISO代码、本地名称或URL编码的名字查找，不区分大小写
+func (c *Catalog) Lookup(name string) (Country, bool) {
+	name = CleanCountry(name)
+	for _, country := range c.Countries {
+		if strings.EqualFold(country.Name, name) || strings.EqualFold(country.ISO, name) ||
+			(country.Local != "" && country.Local == name) {
+			return country, true
+		}
+	}
+	return Country{}, false
+}
+
+// ResolveCountry 把 -country 参数换成ENF国家名：catalog 为 nil 或找不到时只去掉URL编码
+func ResolveCountry(catalog *Catalog, name string) (string, bool) {
+	if catalog != nil {
+		if country, ok := catalog.Lookup(name); ok {
+			return country.Name, true
+		}
+	}
+	return CleanCountry(name), false
+}
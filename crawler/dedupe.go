@@ -0,0 +1,329 @@
+package crawler
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 去重时把两条记录连起来的依据
+const (
+	LinkENF    = "enf"    // 同一个ENF公司ID或详情页路径
+	LinkDomain = "domain" // 官网的注册域名相同
+	LinkEmail  = "email"  // 抓取到的邮箱相同
+	LinkName   = "name"   // 去掉公司类型后的公司名相同
+)
+
+// 比较公司名时去掉的公司类型
+var legalForms = map[string]bool{
+	"gmbh": true, "mbh": true, "ag": true, "kg": true, "ug": true, "ohg": true, "ek": true,
+	"ltd": true, "limited": true, "plc": true, "llp": true, "inc": true, "llc": true, "corp": true, "co": true, "company": true,
+	"srl": true, "spa": true, "snc": true, "sas": true, "sa": true, "sarl": true, "sl": true, "slu": true,
+	"bv": true, "nv": true, "vof": true, "ab": true, "as": true, "aps": true, "oy": true,
+	"sp": true, "zoo": true, "sro": true, "kft": true, "ltda": true, "me": true, "eireli": true, "pty": true,
+}
+
+// 网页模板里的示例邮箱域名，这些邮箱不用来去重
+var placeholderEmailDomains = map[string]bool{
+	"example.com": true, "example.org": true, "ejemplo.com": true, "exemple.fr": true, "exemple.com": true,
+	"beispiel.de": true, "esempio.it": true, "voorbeeld.nl": true, "website.com": true, "domain.com": true,
+	"yourdomain.com": true, "email.com": true, "mail.com": true, "company.com": true,
+}
+
+// 去掉公司类型后太短的公司名（如 Solar、Energy）不用来去重
+const minNameKey = 6
+
+// Membership 主公司在某个目录、国家中的一条记录
+type Membership struct {
+	Category string `json:"category"`
+	Country  string `json:"country"`
+	Number   int    `json:"number"`
+	Name     string `json:"name"`
+	Key      string `json:"key"` // 见 Company.Key，在目录、国家内唯一
+}
+
+// Master 去重后的主公司，包含它在各目录、各国家中的全部记录
+type Master struct {
+	ID           string       `json:"id"` // 见 masterID
+	Name         string       `json:"name"`
+	Website      string       `json:"website"`
+	Email        string       `json:"email"`
+	GuessedEmail string       `json:"guessed_email"` // 只在没有抓取到邮箱时填写
+	Emails       []string     `json:"emails"`        // 全部抓取到的邮箱
+	ENFIDs       []string     `json:"enf_ids"`
+	Categories   []string     `json:"categories"`
+	Countries    []string     `json:"countries"`
+	MatchedBy    []string     `json:"matched_by"` // 实际把记录连起来的依据，见 Link*
+	Members      []Membership `json:"members"`
+}
+
+// unionFind 按下标合并记录
+type unionFind []int
+
+func newUnionFind(n int) unionFind {
+	uf := make(unionFind, n)
+	for i := range uf {
+		uf[i] = i
+	}
+	return uf
+}
+
+func (uf unionFind) find(i int) int {
+	for uf[i] != i {
+		uf[i] = uf[uf[i]]
+		i = uf[i]
+	}
+	return i
+}
+
+func (uf unionFind) union(i, j int) {
+	if ri, rj := uf.find(i), uf.find(j); ri != rj {
+		if rj < ri {
+			ri, rj = rj, ri
+		}
+		uf[rj] = ri
+	}
+}
+
+// Dedupe 把不同目录、不同国家中的同一家公司合并为主公司。
+//
+// ENF ID（或详情页路径）、官网注册域名中任意一项相同的记录属于同一家公司，关系可以传递；
+// 社交/建站平台上的官网不参与比较。之后再比较抓取到的邮箱和公司名（去掉 GmbH、Ltd 等公司类型，
+// 只比较字母数字，至少 minNameKey 个字符）：相同的记录所在的分组合起来最多只有一个官网域名时才合并，
+// 避免网页上的占位邮箱（如 email@example.com）或不同国家的同名公司把不相干的公司连在一起。
+// 猜测的邮箱不参与比较。返回的主公司按ID排序。
+func Dedupe(companies []Company) []Master {
+	uf := newUnionFind(len(companies))
+	keys := make(map[string][]int) // "<依据>:<值>" → 记录下标
+	add := func(kind, value string, i int) {
+		if value != "" {
+			k := kind + ":" + value
+			keys[k] = append(keys[k], i)
+		}
+	}
+	for i, c := range companies {
+		if key := c.Key(); !strings.HasPrefix(key, "n:") {
+			add(LinkENF, key, i)
+		}
+		add(LinkDomain, CompanyDomain(c.Link2), i)
+	}
+	for _, members := range keys {
+		for _, i := range members[1:] {
+			uf.union(members[0], i)
+		}
+	}
+
+	domains := make(map[int]map[string]bool) // 分组 → 官网域名
+	for i, c := range companies {
+		if d := CompanyDomain(c.Link2); d != "" {
+			root := uf.find(i)
+			if domains[root] == nil {
+				domains[root] = make(map[string]bool)
+			}
+			domains[root][d] = true
+		}
+	}
+	weak := func(kind string, value func(Company) string) {
+		groups := make(map[string][]int)
+		for i, c := range companies {
+			if v := value(c); v != "" {
+				groups[v] = append(groups[v], i)
+			}
+		}
+		values := make([]string, 0, len(groups))
+		for v := range groups {
+			values = append(values, v)
+		}
+		sort.Strings(values) // 结果与 map 的遍历顺序无关
+		for _, v := range values {
+			members := groups[v]
+			found := make(map[string]bool)
+			for _, i := range members {
+				for d := range domains[uf.find(i)] {
+					found[d] = true
+				}
+			}
+			if len(members) < 2 || len(found) > 1 {
+				continue
+			}
+			keys[kind+":"+v] = members
+			for _, i := range members[1:] {
+				uf.union(members[0], i)
+			}
+			domains[uf.find(members[0])] = found
+		}
+	}
+	weak(LinkEmail, func(c Company) string {
+		email := strings.ToLower(strings.TrimSpace(c.Email))
+		if at := strings.LastIndex(email, "@"); at < 0 || placeholderEmailDomains[email[at+1:]] {
+			return ""
+		}
+		return email
+	})
+	weak(LinkName, func(c Company) string {
+		if name := nameKey(c.Name); len(name) >= minNameKey {
+			return name
+		}
+		return ""
+	})
+
+	clusters := make(map[int][]int)
+	for i := range companies {
+		root := uf.find(i)
+		clusters[root] = append(clusters[root], i)
+	}
+	matched := make(map[int]map[string]bool)
+	for k, members := range keys {
+		kind := k[:strings.Index(k, ":")]
+		for _, i := range members[1:] {
+			if root := uf.find(i); root == uf.find(members[0]) {
+				if matched[root] == nil {
+					matched[root] = make(map[string]bool)
+				}
+				matched[root][kind] = true
+			}
+		}
+	}
+
+	masters := make([]Master, 0, len(clusters))
+	for root, members := range clusters {
+		group := make([]Company, len(members))
+		for j, i := range members {
+			group[j] = companies[i]
+		}
+		m := newMaster(group)
+		m.MatchedBy = sortedKeys(matched[root])
+		masters = append(masters, m)
+	}
+	sort.Slice(masters, func(i, j int) bool { return masters[i].ID < masters[j].ID })
+	return masters
+}
+
+// nameKey 去掉公司类型后的公司名，只保留小写字母数字
+func nameKey(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return r == ' ' || r == ',' || r == '-' || r == '/' || r == '&' || r == '(' || r == ')'
+	})
+	var b strings.Builder
+	for _, w := range words {
+		w = normalizeName(w)
+		if !legalForms[w] {
+			b.WriteString(w)
+		}
+	}
+	return b.String()
+}
+
+// newMaster 由同一家公司的记录生成主公司。记录按有无抓取到的邮箱、目录、国家、Number 排序，
+// 公司名、官网取第一条有值的记录；邮箱优先取官网上抓取到的，其次ENF详情页的
+func newMaster(group []Company) Master {
+	sort.SliceStable(group, func(i, j int) bool {
+		a, b := group[i], group[j]
+		if (a.Email != "") != (b.Email != "") {
+			return a.Email != ""
+		}
+		if a.Category != b.Category {
+			return a.Category < b.Category
+		}
+		if a.Country != b.Country {
+			return a.Country < b.Country
+		}
+		return a.Number < b.Number
+	})
+	var m Master
+	categories := make(map[string]bool)
+	countries := make(map[string]bool)
+	enfIDs := make(map[string]bool)
+	seen := make(map[string]bool)
+	bestRank := 0
+	for _, c := range group {
+		if m.Name == "" {
+			m.Name = c.Name
+		}
+		if m.Website == "" {
+			m.Website = c.Link2
+		}
+		if email := strings.TrimSpace(c.Email); email != "" && !seen[strings.ToLower(email)] {
+			seen[strings.ToLower(email)] = true
+			m.Emails = append(m.Emails, email)
+			rank := emailRank[c.EmailSource]
+			if c.EmailSource == "" {
+				rank = emailRank[EmailImported]
+			}
+			if rank > bestRank || m.Email == "" {
+				m.Email, bestRank = email, rank
+			}
+		}
+		if m.GuessedEmail == "" {
+			m.GuessedEmail = c.GuessedEmail
+		}
+		if id := ENFID(c.Link1); id != "" {
+			enfIDs[id] = true
+		}
+		if c.Category != "" {
+			categories[c.Category] = true
+		}
+		if c.Country != "" {
+			countries[c.Country] = true
+		}
+		m.Members = append(m.Members, Membership{
+			Category: c.Category, Country: c.Country, Number: c.Number, Name: c.Name, Key: c.Key(),
+		})
+	}
+	if m.Email != "" {
+		m.GuessedEmail = ""
+	}
+	m.ENFIDs = sortedKeys(enfIDs)
+	m.Categories = sortedKeys(categories)
+	m.Countries = sortedKeys(countries)
+	m.ID = masterID(m, group)
+	return m
+}
+
+// masterID 主公司的标识，同样的输入在不同次运行中相同：最小的ENF ID，其次官网域名、邮箱，
+// 都没有时用第一条记录的目录、国家和 Key
+func masterID(m Master, group []Company) string {
+	if len(m.ENFIDs) > 0 {
+		ids := append([]string(nil), m.ENFIDs...)
+		sort.Slice(ids, func(i, j int) bool {
+			a, errA := strconv.Atoi(ids[i])
+			b, errB := strconv.Atoi(ids[j])
+			if errA == nil && errB == nil {
+				return a < b
+			}
+			return ids[i] < ids[j]
+		})
+		return LinkENF + ":" + ids[0]
+	}
+	var domains, emails, keys []string
+	for _, c := range group {
+		if d := CompanyDomain(c.Link2); d != "" {
+			domains = append(domains, d)
+		}
+		if c.Email != "" {
+			emails = append(emails, strings.ToLower(strings.TrimSpace(c.Email)))
+		}
+		keys = append(keys, c.Category+"/"+c.Country+"/"+c.Key())
+	}
+	sort.Strings(domains)
+	sort.Strings(emails)
+	sort.Strings(keys)
+	switch {
+	case len(domains) > 0:
+		return LinkDomain + ":" + domains[0]
+	case len(emails) > 0:
+		return LinkEmail + ":" + emails[0]
+	case len(keys) > 0:
+		return keys[0]
+	}
+	return ""
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,52 @@
+package crawler
+
+import (
+	"regexp"
+	"strings"
+)
+
+// 支持 @、[at]、(at) 三种写法，允许中间有空格
+var emailPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)[\w\.-]+\s*@\s*[\w\.-]+\.[a-zA-Z]{2,}`),
+	regexp.MustCompile(`(?i)[\w\.-]+\s*\[at\]\s*[\w\.-]+\.[a-zA-Z]{2,}`),
+	regexp.MustCompile(`(?i)[\w\.-]+\s*\(at\)\s*[\w\.-]+\.[a-zA-Z]{2,}`),
+}
+
+// ExtractEmail 从网页文本中提取第一个邮箱
+func ExtractEmail(text string) string {
+	for _, re := range emailPatterns {
+		match := re.FindString(text)
+		if match != "" {
+			return normalizeEmail(match)
+		}
+	}
+	return ""
+}
+
+// ExtractEmails 从网页文本中提取所有邮箱，去重后按出现顺序（@ 写法优先）返回
+func ExtractEmails(text string) []string {
+	seen := make(map[string]bool)
+	var emails []string
+	for _, re := range emailPatterns {
+		for _, match := range re.FindAllString(text, -1) {
+			email := normalizeEmail(match)
+			if key := strings.ToLower(email); !seen[key] {
+				seen[key] = true
+				emails = append(emails, email)
+			}
+		}
+	}
+	return emails
+}
+
+// 标准化邮箱
+func normalizeEmail(match string) string {
+	match = strings.ReplaceAll(match, "(at)", "@")
+	match = strings.ReplaceAll(match, "[at]", "@")
+	match = strings.ReplaceAll(match, "(AT)", "@")
+	match = strings.ReplaceAll(match, "[AT]", "@")
+	match = strings.ReplaceAll(match, " ", "")
+	match = strings.ReplaceAll(match, "\n", "")
+	match = strings.ReplaceAll(match, "\r", "")
+	return strings.TrimSpace(match)
+}
@@ -0,0 +1,90 @@
+package crawler
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ENFBaseURL ENF站点地址
+var ENFBaseURL = "https://www.enf.com.cn"
+
+// ENF 并发过高时详情页返回的占位邮箱
+const ENFThrottleEmail = "alan@enfsolar.com"
+
+var (
+	reEEE    = regexp.MustCompile(`let\s+eee\s*=\s*['"]([^'"]+)['"]`)
+	reMailto = regexp.MustCompile(`mailto:([a-zA-Z0-9_.+-]+@[a-zA-Z0-9-]+\.[a-zA-Z0-9-.]+)`)
+	rePlain  = regexp.MustCompile(`[a-zA-Z0-9_.+-]+@[a-zA-Z0-9-]+\.[a-zA-Z0-9-.]+`)
+)
+
+// DefaultENFConfig ENF页面抓取参数：校验证书且不降级，失败时走代理
+func DefaultENFConfig() FetchConfig {
+	return FetchConfig{
+		Timeout:        10 * time.Second,
+		ProxyTimeout:   10 * time.Second,
+		ReadTimeout:    5 * time.Second,
+		AcceptLanguage: "zh-CN,zh;q=0.9,en;q=0.8",
+		UseProxy:       true,
+	}
+}
+
+// ENFURL 补全ENF站内链接
+func ENFURL(link string) string {
+	if strings.HasPrefix(link, "http") {
+		return link
+	}
+	return ENFBaseURL + link
+}
+
+// DirectoryURL 目录列表页地址，如 https://www.enf.com.cn/directory/installer/United%20States?page=2；
+// 路径见 Category.Path
+func DirectoryURL(category, country string, page int) string {
+	path := category
+	if c, err := LookupCategory(category); err == nil {
+		path = c.Path
+	}
+	u := fmt.Sprintf("%s/directory/%s/%s", ENFBaseURL, path, url.PathEscape(country))
+	if page > 1 {
+		u += fmt.Sprintf("?page=%d", page)
+	}
+	return u
+}
+
+// ExtractProfileEmail 增强邮箱提取，支持 let eee 编码、mailto、明文三种
+func ExtractProfileEmail(html string) string {
+	// 1. 先找 let eee = 'xxx' 形式
+	if m := reEEE.FindStringSubmatch(html); len(m) > 1 {
+		email := strings.Replace(m[1], "#109#103#.cn", "@", 1)
+		email = strings.Replace(email, "#103#example123cn", ".com", 1)
+		if strings.Contains(email, "@") {
+			return email
+		}
+	}
+	// 2. 再找 mailto:xxx@xxx
+	if m := reMailto.FindStringSubmatch(html); len(m) > 1 {
+		return m[1]
+	}
+	// 3. 再找明文邮箱
+	return rePlain.FindString(html)
+}
+
+// ENFID 详情页链接中的公司ID（utm_content 参数），如 .../100-powerlink?...&utm_content=124706 → 124706
+func ENFID(link1 string) string {
+	u, err := url.Parse(strings.TrimSpace(link1))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(u.Query().Get("utm_content"))
+}
+
+// ENFSlug 详情页链接的路径，如 https://www.enf.com.cn/100-powerlink?... → 100-powerlink
+func ENFSlug(link1 string) string {
+	u, err := url.Parse(strings.TrimSpace(link1))
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(strings.Trim(u.Path, "/"))
+}
@@ -0,0 +1,431 @@
+package crawler
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"golang.org/x/net/proxy"
+)
+
+const UserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36"
+
+// 单个页面最多读取的字节数
+const maxBodySize = 8 << 20
+
+// ProxyAccount 代理账号信息
+type ProxyAccount struct {
+	Username string
+	Password string
+}
+
+// ProxyAccounts webshare 轮换代理账号
+var ProxyAccounts = []ProxyAccount{
+	{"yangyangmao-rotate", "yangyangmao"},
+	{"iu7zso75luk-rotate", "iu7zso75luk"},
+	{"shengshi-rotate", "shengshi"},
+	{"xixiwenxuanhe-rotate", "xixiwenxuanhe"},
+}
+
+var ErrAllProxiesFailed = errors.New("所有代理均请求失败")
+
+// FetchConfig 抓取参数
+type FetchConfig struct {
+	Timeout             time.Duration // 本地网络总超时
+	ProxyTimeout        time.Duration // 代理网络总超时
+	ReadTimeout         time.Duration // 解压读取时单次Read的超时
+	AcceptLanguage      string
+	ProxyAcceptLanguage string           // 走代理时的 Accept-Language，为空时与 AcceptLanguage 相同
+	UseProxy            bool             // 直连失败或非200时改走代理
+	ProxyOffset         int              // 从第几个代理账号开始尝试
+	InsecureFallback    bool             // 证书校验失败时跳过校验重试，只用于公司官网
+	TLS                 *TLSPolicy       // 证书白名单
+	Robots              bool             // 遵守robots.txt的Disallow和Crawl-delay，请求改用 RobotsUserAgent
+	Cache               *Cache           // 响应缓存，为nil时不缓存
+	CacheTTL            time.Duration    // 缓存有效期，<=0 表示永不过期
+	Offline             bool             // 只读缓存，不访问网络
+	Limiter             *AdaptiveLimiter // 自适应并发控制，为nil时只受worker数量限制
+}
+
+// DefaultFetchConfig 公司官网抓取的默认参数
+func DefaultFetchConfig() FetchConfig {
+	return FetchConfig{
+		Timeout:             5 * time.Second, // 本地网络最多5秒
+		ProxyTimeout:        3 * time.Second, // 代理网络最多3秒
+		ReadTimeout:         3 * time.Second,
+		AcceptLanguage:      "zh-CN,zh;q=0.9,en;q=0.8",
+		ProxyAcceptLanguage: "de-DE,de;q=0.9,en;q=0.8", // 与原 procedure1 一致，代理请求使用德语首选
+		UseProxy:            true,
+		InsecureFallback:    true,
+	}
+}
+
+// Response 解压后的响应
+type Response struct {
+	URL        string
+	FinalURL   string
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	UsedProxy  bool
+	Insecure   bool   // 是否跳过了证书校验
+	TLSError   string // 校验失败时的TLS错误类别
+	FetchedAt  time.Time
+	FromCache  bool
+}
+
+// FetchError 抓取失败，带上途中遇到的TLS错误类别
+type FetchError struct {
+	URL      string
+	TLSError string
+	Err      error
+}
+
+func (e *FetchError) Error() string {
+	if e.TLSError != "" {
+		return fmt.Sprintf("%v [tls: %s]", e.Err, e.TLSError)
+	}
+	return e.Err.Error()
+}
+
+func (e *FetchError) Unwrap() error { return e.Err }
+
+// TLSErrorOf 取出错误中记录的TLS错误类别
+func TLSErrorOf(err error) string {
+	var fe *FetchError
+	if errors.As(err, &fe) {
+		return fe.TLSError
+	}
+	return ClassifyTLSError(err)
+}
+
+// Fetcher 共享的HTTP客户端，所有并发任务共用
+type Fetcher struct {
+	cfg      FetchConfig
+	secure   *http.Client
+	insecure *http.Client
+	proxies  []proxyClient
+	robots   *RobotsCache
+}
+
+type proxyClient struct {
+	secure   *http.Client
+	insecure *http.Client
+}
+
+// NewFetcher 按配置创建抓取器
+func NewFetcher(cfg FetchConfig) *Fetcher {
+	f := &Fetcher{
+		cfg:      cfg,
+		secure:   newDirectClient(cfg.Timeout, false),
+		insecure: newDirectClient(cfg.Timeout, true),
+	}
+	if cfg.Robots {
+		// robots.txt 内容不敏感，下载时不校验证书
+		f.robots = NewRobotsCache(f.insecure)
+	}
+	if cfg.UseProxy {
+		for _, account := range ProxyAccounts {
+			proxyURL := fmt.Sprintf("socks5://%s:%s@p.webshare.io:80", account.Username, account.Password)
+			dialer, err := createProxyDialer(proxyURL)
+			if err != nil {
+				continue
+			}
+			f.proxies = append(f.proxies, proxyClient{
+				secure:   newProxyClient(dialer, cfg.ProxyTimeout, false),
+				insecure: newProxyClient(dialer, cfg.ProxyTimeout, true),
+			})
+		}
+	}
+	return f
+}
+
+// 各阶段超时按总超时的比例设置，默认5秒时为连接2秒、握手2秒、响应头3秒
+func newDirectClient(timeout time.Duration, insecure bool) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext: (&net.Dialer{
+				Timeout:   timeout * 2 / 5,  // 连接超时
+				KeepAlive: 30 * time.Second, // 保持连接
+			}).DialContext,
+			TLSHandshakeTimeout:   timeout * 2 / 5,
+			ResponseHeaderTimeout: timeout * 3 / 5,
+			ExpectContinueTimeout: 1 * time.Second,
+			MaxIdleConns:          100,
+			MaxIdleConnsPerHost:   4,
+			IdleConnTimeout:       30 * time.Second,
+			TLSClientConfig:       tlsConfig(insecure),
+		},
+	}
+}
+
+// 默认3秒时为连接1秒、握手2秒、响应头2秒
+func newProxyClient(dialer proxy.Dialer, timeout time.Duration, insecure bool) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				// 设置连接超时
+				ctx, cancel := context.WithTimeout(ctx, timeout/3)
+				defer cancel()
+				if cd, ok := dialer.(proxy.ContextDialer); ok {
+					return cd.DialContext(ctx, network, addr)
+				}
+				return dialer.Dial(network, addr)
+			},
+			TLSClientConfig: tlsConfig(insecure),
+			// 设置TLS握手超时
+			TLSHandshakeTimeout: timeout * 2 / 3,
+			// 设置响应头超时
+			ResponseHeaderTimeout: timeout * 2 / 3,
+		},
+	}
+}
+
+// 创建代理拨号器
+func createProxyDialer(proxyURL string) (proxy.Dialer, error) {
+	parsedURL, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("解析代理URL失败: %v", err)
+	}
+	auth := &proxy.Auth{}
+	if parsedURL.User != nil {
+		auth.User = parsedURL.User.Username()
+		if password, ok := parsedURL.User.Password(); ok {
+			auth.Password = password
+		}
+	}
+	dialer, err := proxy.SOCKS5("tcp", parsedURL.Host, auth, proxy.Direct)
+	if err != nil {
+		return nil, fmt.Errorf("创建SOCKS5代理拨号器失败: %v", err)
+	}
+	return dialer, nil
+}
+
+// Fetch 请求页面：先直连并校验证书，证书失败时按配置跳过校验重试，
+// 直连失败或非200时再依次尝试代理。返回的Body已解压。
+// 开启robots模式时，被禁止的URL返回 ErrRobotsDisallowed，缓存中有也不返回。
+// 配置了缓存时优先读缓存；离线模式下缓存未命中返回 ErrCacheMiss。
+func (f *Fetcher) Fetch(ctx context.Context, target string) (*Response, error) {
+	if f.robots != nil {
+		if err := f.robots.Check(ctx, target); err != nil {
+			return nil, &FetchError{URL: target, Err: err}
+		}
+	}
+	if f.cfg.Cache != nil {
+		maxAge := f.cfg.CacheTTL
+		if f.cfg.Offline {
+			maxAge = 0 // 离线时过期的缓存也用
+		}
+		if resp, ok := f.cfg.Cache.Get(target, maxAge); ok {
+			return resp, nil
+		}
+	}
+	if f.cfg.Offline {
+		return nil, &FetchError{URL: target, Err: ErrCacheMiss}
+	}
+	resp, err := f.fetch(ctx, target)
+	if err == nil && f.cfg.Cache != nil {
+		if cerr := f.cfg.Cache.Put(resp); cerr != nil {
+			log.Printf("写入缓存失败 %s：%v\n", target, cerr)
+		}
+	}
+	return resp, err
+}
+
+func (f *Fetcher) fetch(ctx context.Context, target string) (*Response, error) {
+	if f.robots != nil {
+		if err := f.robots.Wait(ctx, target); err != nil {
+			return nil, &FetchError{URL: target, Err: err}
+		}
+	}
+	overloaded := false
+	if f.cfg.Limiter != nil {
+		if err := f.cfg.Limiter.Acquire(ctx); err != nil {
+			return nil, &FetchError{URL: target, Err: err}
+		}
+		started := time.Now()
+		defer func() { f.cfg.Limiter.Release(time.Since(started), overloaded) }()
+	}
+
+	host := hostOf(target)
+	insecure := f.cfg.TLS.Trusted(host)
+	tlsClass := ""
+
+	resp, err := f.do(ctx, f.client(insecure), target, f.cfg.AcceptLanguage)
+	if err != nil && !insecure {
+		if class := ClassifyTLSError(err); class != "" {
+			tlsClass = class
+			if f.cfg.InsecureFallback && isCertError(class) {
+				insecure = true
+				resp, err = f.do(ctx, f.insecure, target, f.cfg.AcceptLanguage)
+			}
+		}
+	}
+	overloaded = isOverload(resp, err)
+
+	usedProxy := false
+	if f.cfg.UseProxy && (err != nil || resp.StatusCode != http.StatusOK) {
+		if resp != nil {
+			resp.Body.Close()
+		}
+		proxyResp, proxyErr := f.viaProxy(ctx, target, insecure)
+		if proxyErr != nil {
+			if err == nil {
+				err = fmt.Errorf("状态码: %d", resp.StatusCode)
+			}
+			return nil, &FetchError{URL: target, TLSError: tlsClass, Err: fmt.Errorf("%w (直连: %v)", proxyErr, err)}
+		}
+		resp, err = proxyResp, nil
+		usedProxy = true
+	}
+	if err != nil {
+		return nil, &FetchError{URL: target, TLSError: tlsClass, Err: err}
+	}
+	defer resp.Body.Close()
+
+	body, err := f.readBody(resp)
+	if err != nil {
+		return nil, &FetchError{URL: target, TLSError: tlsClass, Err: err}
+	}
+	return &Response{
+		URL:        target,
+		FinalURL:   resp.Request.URL.String(),
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       body,
+		UsedProxy:  usedProxy,
+		Insecure:   insecure,
+		TLSError:   tlsClass,
+		FetchedAt:  time.Now(),
+	}, nil
+}
+
+func (f *Fetcher) client(insecure bool) *http.Client {
+	if insecure {
+		return f.insecure
+	}
+	return f.secure
+}
+
+// 使用代理请求网页，从 ProxyOffset 开始轮流尝试，返回第一个200响应
+func (f *Fetcher) viaProxy(ctx context.Context, target string, insecure bool) (*http.Response, error) {
+	for i := range f.proxies {
+		p := f.proxies[(i+f.cfg.ProxyOffset)%len(f.proxies)]
+		client := p.secure
+		if insecure {
+			client = p.insecure
+		}
+		resp, err := f.do(ctx, client, target, f.proxyAcceptLanguage())
+		if err != nil {
+			continue
+		}
+		if resp.StatusCode == http.StatusOK {
+			return resp, nil
+		}
+		resp.Body.Close()
+	}
+	return nil, ErrAllProxiesFailed
+}
+
+func (f *Fetcher) proxyAcceptLanguage() string {
+	if f.cfg.ProxyAcceptLanguage != "" {
+		return f.cfg.ProxyAcceptLanguage
+	}
+	return f.cfg.AcceptLanguage
+}
+
+func (f *Fetcher) do(ctx context.Context, client *http.Client, target, acceptLanguage string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", target, nil)
+	if err != nil {
+		return nil, err
+	}
+	setBrowserHeaders(req, acceptLanguage)
+	if f.robots != nil {
+		req.Header.Set("User-Agent", RobotsUserAgent)
+	}
+	return client.Do(req)
+}
+
+// 添加完整的浏览器请求头
+func setBrowserHeaders(req *http.Request, acceptLanguage string) {
+	if acceptLanguage == "" {
+		acceptLanguage = "zh-CN,zh;q=0.9,en;q=0.8"
+	}
+	req.Header.Set("User-Agent", UserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+	req.Header.Set("Accept-Language", acceptLanguage)
+	req.Header.Set("Accept-Encoding", "gzip, deflate, br")
+	req.Header.Set("Connection", "keep-alive")
+	req.Header.Set("Referer", "https://www.google.com/")
+	req.Header.Set("Upgrade-Insecure-Requests", "1")
+	req.Header.Set("Cache-Control", "max-age=0")
+	req.Header.Set("Pragma", "no-cache")
+}
+
+// 处理可能的压缩响应
+func (f *Fetcher) readBody(resp *http.Response) ([]byte, error) {
+	var reader io.Reader = resp.Body
+	switch resp.Header.Get("Content-Encoding") {
+	case "gzip":
+		gzr, err := gzip.NewReader(&timeoutReader{r: resp.Body, timeout: f.cfg.ReadTimeout})
+		if err != nil {
+			return nil, fmt.Errorf("解压gzip失败: %v", err)
+		}
+		defer gzr.Close()
+		reader = gzr
+	case "br":
+		reader = brotli.NewReader(&timeoutReader{r: resp.Body, timeout: f.cfg.ReadTimeout})
+	}
+	body, err := io.ReadAll(io.LimitReader(reader, maxBodySize))
+	if err != nil {
+		return nil, fmt.Errorf("读取页面失败: %v", err)
+	}
+	return body, nil
+}
+
+func hostOf(target string) string {
+	u, err := url.Parse(target)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// 超时读取器，用于处理读取超时
+type timeoutReader struct {
+	r       io.Reader
+	timeout time.Duration
+}
+
+type readResult struct {
+	n   int
+	err error
+}
+
+func (tr *timeoutReader) Read(p []byte) (n int, err error) {
+	if tr.timeout <= 0 {
+		return tr.r.Read(p)
+	}
+	ch := make(chan readResult, 1)
+	go func() {
+		n, err := tr.r.Read(p)
+		ch <- readResult{n, err}
+	}()
+	select {
+	case res := <-ch:
+		return res.n, res.err
+	case <-time.After(tr.timeout):
+		return 0, errors.New("读取超时")
+	}
+}
@@ -0,0 +1,112 @@
+package crawler
+
+import (
+	"net"
+	"net/url"
+	"strings"
+)
+
+// 各国常用的通用邮箱前缀，按常见程度排列；没有列出的国家只猜 info@
+var guessPrefixes = map[string][]string{
+	"germany":     {"info", "kontakt"},
+	"austria":     {"office", "info", "kontakt"},
+	"switzerland": {"info", "kontakt"},
+	"poland":      {"kontakt", "info"},
+	"denmark":     {"kontakt", "info"},
+	"norway":      {"kontakt", "info"},
+	"sweden":      {"info", "kontakt"},
+	"france":      {"contact", "info"},
+	"belgium":     {"info", "contact"},
+	"romania":     {"contact", "office"},
+	"morocco":     {"contact", "info"},
+	"brazil":      {"vendas", "contato"},
+	"portugal":    {"vendas", "info"},
+}
+
+// 这些域名上的官网链接是平台页面而不是公司自己的域名，不能据此猜邮箱
+var guessSkipDomains = map[string]bool{
+	"facebook.com":     true,
+	"instagram.com":    true,
+	"linkedin.com":     true,
+	"twitter.com":      true,
+	"x.com":            true,
+	"youtube.com":      true,
+	"google.com":       true,
+	"business.site":    true,
+	"wixsite.com":      true,
+	"wordpress.com":    true,
+	"blogspot.com":     true,
+	"weebly.com":       true,
+	"jimdo.com":        true,
+	"jimdosite.com":    true,
+	"webnode.com":      true,
+	"webnode.es":       true,
+	"webnode.page":     true,
+	"jimdofree.com":    true,
+	"ueniweb.com":      true,
+	"celseo.de":        true,
+	"site123.me":       true,
+	"godaddysites.com": true,
+	"mystrikingly.com": true,
+	"squarespace.com":  true,
+	"yell.com":         true,
+	"enfsolar.com":     true,
+	"enf.com.cn":       true,
+	"alibaba.com":      true,
+	"indiamart.com":    true,
+	"tradeindia.com":   true,
+}
+
+// GuessEmails 按官网的注册域名和国家生成候选邮箱，常用的排在前面。
+// 没有官网、官网是IP地址或社交/建站平台页面时返回 nil。
+// 猜出的邮箱没有经过验证，只能放在 GuessedEmail 中，不能当作抓取到的邮箱使用。
+func GuessEmails(website, country string) []string {
+	domain := CompanyDomain(website)
+	if domain == "" {
+		return nil
+	}
+	prefixes := guessPrefixes[strings.ToLower(strings.TrimSpace(country))]
+	if len(prefixes) == 0 {
+		prefixes = []string{"info"}
+	}
+	emails := make([]string, len(prefixes))
+	for i, prefix := range prefixes {
+		emails[i] = prefix + "@" + domain
+	}
+	return emails
+}
+
+// CompanyDomain 官网的注册域名；没有官网、官网是IP地址或社交/建站平台页面时返回空
+func CompanyDomain(website string) string {
+	website = strings.TrimSpace(website)
+	if website == "" {
+		return ""
+	}
+	if !strings.Contains(website, "://") {
+		website = "http://" + website
+	}
+	u, err := url.Parse(website)
+	if err != nil || u.Hostname() == "" || net.ParseIP(u.Hostname()) != nil {
+		return ""
+	}
+	domain := RegisteredDomain(website)
+	if !strings.Contains(domain, ".") || guessSkipDomains[domain] {
+		return ""
+	}
+	return domain
+}
+
+// GuessEmail 最可能的候选邮箱，见 GuessEmails
+func GuessEmail(website, country string) string {
+	if emails := GuessEmails(website, country); len(emails) > 0 {
+		return emails[0]
+	}
+	return ""
+}
+
+// IsLegacyGuess email 是否为 procedure2.py 按公司名猜测的 info@<小写字母数字>.com，
+// 这样的地址在旧的 Procedure2 文件里和抓取到的邮箱混在 Email 列中
+func IsLegacyGuess(email, name string) bool {
+	name = normalizeName(name)
+	return name != "" && strings.EqualFold(strings.TrimSpace(email), "info@"+name+".com")
+}
@@ -0,0 +1,182 @@
+package crawler
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// InputFile 从文件名解析出的信息，文件名格式为 <type>_<country>_<Stage><YYYYMMDD>.csv，
+// 如 installer_United Kingdom_Company20250508.csv；旧文件中URL编码的国家名（United%20Kingdom）同样可以解析
+type InputFile struct {
+	Path    string
+	Type    string // installer / seller
+	Country string // 已解码，如 United Kingdom
+	Stage   string // Company / Procedure1 / Procedure2 / Email / Pipeline
+	Date    string // YYYYMMDD
+}
+
+var reInputName = regexp.MustCompile(`^([A-Za-z]+)_(.+)_([A-Za-z]+[0-9]?)([0-9]{8})\.csv$`)
+
+// OutputName 输出文件名 <type>_<country>_<stage><date>.<ext>，国家名不做URL编码，见 CleanCountry
+func OutputName(typ, country, stage, date, ext string) string {
+	return fmt.Sprintf("%s_%s_%s%s.%s", typ, CleanCountry(country), stage, date, ext)
+}
+
+// ParseInputName 解析文件名，不符合格式时返回 false
+func ParseInputName(path string) (InputFile, bool) {
+	m := reInputName.FindStringSubmatch(filepath.Base(path))
+	if m == nil {
+		return InputFile{Path: path}, false
+	}
+	country, err := url.PathUnescape(m[2])
+	if err != nil {
+		country = m[2]
+	}
+	return InputFile{Path: path, Type: m[1], Country: country, Stage: m[3], Date: m[4]}, true
+}
+
+// InputFilter 选择输入文件的条件，为空的条件不过滤
+type InputFilter struct {
+	Types     []string // installer / seller
+	Countries []string // 国家名，不区分大小写，%20 与空格等价
+	Stage     string   // 只要某一阶段的文件，如 Company
+	Latest    bool     // 同一类型、国家、阶段只保留日期最新的文件
+}
+
+// SplitList 拆分逗号分隔的命令行参数，去掉空白和空项
+func SplitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func (f InputFilter) match(in InputFile) bool {
+	if f.Stage != "" && !strings.EqualFold(in.Stage, f.Stage) {
+		return false
+	}
+	if len(f.Types) > 0 && !containsFold(f.Types, in.Type) {
+		return false
+	}
+	if len(f.Countries) > 0 && !containsFold(f.Countries, in.Country) {
+		return false
+	}
+	return true
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if decoded, err := url.PathUnescape(item); err == nil {
+			item = decoded
+		}
+		if strings.EqualFold(strings.TrimSpace(item), s) {
+			return true
+		}
+	}
+	return false
+}
+
+// DiscoverInputs 展开目录（其中的 *.csv）、通配符和文件路径，解析文件名并按条件过滤，
+// 结果按类型、国家、日期排序。文件名不符合格式的文件跳过。
+func DiscoverInputs(patterns []string, filter InputFilter) ([]InputFile, error) {
+	seen := make(map[string]bool)
+	var found []InputFile
+	for _, pattern := range patterns {
+		paths, err := expandInput(pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			if seen[path] {
+				continue
+			}
+			seen[path] = true
+			in, ok := ParseInputName(path)
+			if !ok || !filter.match(in) {
+				continue
+			}
+			found = append(found, in)
+		}
+	}
+	if filter.Latest {
+		found = latestInputs(found)
+	}
+	sort.Slice(found, func(i, j int) bool {
+		a, b := found[i], found[j]
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if a.Country != b.Country {
+			return a.Country < b.Country
+		}
+		if a.Stage != b.Stage {
+			return a.Stage < b.Stage
+		}
+		return a.Date < b.Date
+	})
+	return found, nil
+}
+
+func expandInput(pattern string) ([]string, error) {
+	info, err := os.Stat(pattern)
+	if err == nil && info.IsDir() {
+		return filepath.Glob(filepath.Join(pattern, "*.csv"))
+	}
+	if err == nil {
+		return []string{pattern}, nil
+	}
+	paths, gerr := filepath.Glob(pattern)
+	if gerr != nil {
+		return nil, fmt.Errorf("无效的通配符 %s: %v", pattern, gerr)
+	}
+	if len(paths) == 0 && !strings.ContainsAny(pattern, "*?[") {
+		return nil, fmt.Errorf("找不到输入文件 %s", pattern)
+	}
+	return paths, nil
+}
+
+// latestInputs 同一类型、国家、阶段只保留日期最新的文件
+func latestInputs(files []InputFile) []InputFile {
+	latest := make(map[string]InputFile)
+	for _, in := range files {
+		key := in.Type + "\x00" + strings.ToLower(in.Country) + "\x00" + in.Stage
+		if cur, ok := latest[key]; !ok || in.Date > cur.Date {
+			latest[key] = in
+		}
+	}
+	out := make([]InputFile, 0, len(latest))
+	for _, in := range latest {
+		out = append(out, in)
+	}
+	return out
+}
+
+// FindCompanyFile dir 中与 in 同一类型、国家的 Company 文件，优先用与 in 同一天的，没有时用最新的
+func FindCompanyFile(in InputFile, dir string) (InputFile, error) {
+	files, err := DiscoverInputs([]string{dir}, InputFilter{
+		Types:     []string{in.Type},
+		Countries: []string{in.Country},
+		Stage:     "Company",
+	})
+	if err != nil {
+		return InputFile{}, err
+	}
+	if len(files) == 0 {
+		return InputFile{}, fmt.Errorf("%s 中没有 %s_%s 的 Company 文件", dir, in.Type, in.Country)
+	}
+	found := files[len(files)-1]
+	for _, f := range files {
+		if f.Date == in.Date {
+			found = f
+		}
+	}
+	return found, nil
+}
@@ -0,0 +1,54 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// ListingEntry 列表页上的一行公司
+type ListingEntry struct {
+	Name    string
+	Address string
+	Link1   string
+	Details map[string]string // 目录特有的列，见 Category.Columns
+}
+
+// DataEvent 列表页公司链接上的 data-event，如 cl_installer_united_states_clk
+func DataEvent(category, country string) string {
+	c := strings.ToLower(strings.ReplaceAll(country, " ", "_"))
+	return fmt.Sprintf("cl_%s_%s_clk", category, c)
+}
+
+// ParseListing 解析目录列表页
+func ParseListing(body []byte, dataEvent string) ([]ListingEntry, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("解析列表页失败: %v", err)
+	}
+	var entries []ListingEntry
+	doc.Find("tr.mkjs-el").Each(func(_ int, row *goquery.Selection) {
+		address := strings.TrimSpace(row.Find("td.no-left-right-padding").First().Text())
+		link := row.Find(fmt.Sprintf(`a[data-event="%s"]`, dataEvent)).First()
+		name := strings.TrimSpace(link.Text())
+		link1, _ := link.Attr("href")
+		// 过滤无效公司（如广告或特殊页）
+		if name == "" || link1 == "" {
+			return
+		}
+		entries = append(entries, ListingEntry{Name: name, Address: address, Link1: ENFURL(link1)})
+	})
+	return entries, nil
+}
+
+// FetchListing 抓取并按目录的解析方式解析一页列表
+func FetchListing(ctx context.Context, f *Fetcher, cat Category, country string, page int) ([]ListingEntry, error) {
+	resp, err := f.Fetch(ctx, DirectoryURL(cat.Name, country, page))
+	if err != nil {
+		return nil, err
+	}
+	return cat.Parse(resp.Body, cat, country)
+}
@@ -0,0 +1,184 @@
+package crawler
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// 合并时发现的问题
+const (
+	IssueOrphan    = "orphan"    // 阶段文件中的行找不到对应的公司
+	IssueMissing   = "missing"   // 公司在某个阶段文件中没有对应的行
+	IssueDuplicate = "duplicate" // 同一阶段中多行对应同一家公司，只用第一行
+	IssueConflict  = "conflict"  // 同一字段在不同阶段的值不同，按优先顺序取值
+)
+
+// StageRows 一个阶段文件中的记录
+type StageRows struct {
+	Stage string // Procedure1 / Email / Procedure2 ...
+	Path  string
+	Rows  []Company
+}
+
+// MergeIssue 合并报告中的一行
+type MergeIssue struct {
+	Kind    string
+	Stage   string
+	Number  int // 阶段文件中的 Number；missing 时为 Company 中的 Number
+	Name    string
+	Key     string
+	Field   string // conflict 时的字段
+	Kept    string // conflict 时采用的值
+	Dropped string // conflict 时舍弃的值
+}
+
+// 阶段的合并顺序，没有列出的阶段排在最后
+var stageOrder = map[string]int{"Procedure1": 1, "Email": 2, "Procedure2": 3, "Pipeline": 4}
+
+// MergeStages 以 Company 文件的记录为准，把各阶段的行按稳定的公司标识合并进来。
+//
+// 匹配顺序：
+//  1. 行中有ENF详情页链接（seller 各阶段的 Website 列）时按 Company.Key（ENF ID / 路径）匹配；
+//  2. 否则按 Number 匹配，且公司名（只比较字母数字）必须相同；
+//  3. Number 对不上时按公司名匹配，公司名在 Company 中唯一才算数；
+//  4. 都不行的行记为 orphan，不合并。
+//
+// 取值的优先顺序（先到先得，后面的阶段只补空值，不同的值记为 conflict）：
+//   - Number、Company Name、Address、Link1：Company（ENF列表）
+//   - 官网：Company → Procedure1 → Email → Procedure2
+//   - Email：Procedure1 → Email → Procedure2，即抓取到的邮箱优先；
+//     Procedure2 中 procedure2.py 按公司名猜的 info@<公司名>.com 不算邮箱，放入 GuessedEmail
+//   - Outcome、Pass、TLS：最先有值的阶段
+func MergeStages(companies []Company, stages ...StageRows) ([]Company, []MergeIssue) {
+	merged := make([]Company, len(companies))
+	copy(merged, companies)
+	byKey := make(map[string]int)
+	byNumber := make(map[int]int)
+	byName := make(map[string]int)
+	for i, c := range merged {
+		// 没有ENF链接的 Company 行只能按 Number 或公司名匹配
+		if key := c.Key(); !strings.HasPrefix(key, "n:") {
+			byKey[key] = i
+		}
+		byNumber[c.Number] = i
+		name := normalizeName(c.Name)
+		if _, dup := byName[name]; dup {
+			byName[name] = -1
+		} else {
+			byName[name] = i
+		}
+	}
+
+	sorted := make([]StageRows, len(stages))
+	copy(sorted, stages)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return stageRank(sorted[i].Stage) < stageRank(sorted[j].Stage)
+	})
+
+	var issues []MergeIssue
+	for _, stage := range sorted {
+		seen := make(map[int]bool)
+		for _, r := range stage.Rows {
+			i, ok := matchCompany(r, merged, byKey, byNumber, byName)
+			issue := MergeIssue{Stage: stage.Stage, Number: r.Number, Name: r.Name, Key: r.Key()}
+			switch {
+			case !ok:
+				issue.Kind = IssueOrphan
+				issues = append(issues, issue)
+				continue
+			case seen[i]:
+				issue.Kind = IssueDuplicate
+				issues = append(issues, issue)
+				continue
+			}
+			seen[i] = true
+			issues = append(issues, mergeRow(&merged[i], r, stage.Stage)...)
+		}
+		for i, c := range merged {
+			if !seen[i] {
+				issues = append(issues, MergeIssue{Kind: IssueMissing, Stage: stage.Stage, Number: c.Number, Name: c.Name, Key: c.Key()})
+			}
+		}
+	}
+	return merged, issues
+}
+
+func stageRank(stage string) int {
+	if rank, ok := stageOrder[stage]; ok {
+		return rank
+	}
+	return len(stageOrder) + 1
+}
+
+func matchCompany(r Company, merged []Company, byKey map[string]int, byNumber map[int]int, byName map[string]int) (int, bool) {
+	key := r.Key()
+	if !strings.HasPrefix(key, "n:") {
+		i, ok := byKey[key]
+		return i, ok
+	}
+	name := normalizeName(r.Name)
+	if i, ok := byNumber[r.Number]; ok && normalizeName(merged[i].Name) == name {
+		return i, true
+	}
+	if i, ok := byName[name]; ok && i >= 0 && name != "" {
+		return i, true
+	}
+	return 0, false
+}
+
+// mergeRow 按优先顺序把阶段中的一行并入 c，返回冲突
+func mergeRow(c *Company, r Company, stage string) []MergeIssue {
+	var issues []MergeIssue
+	set := func(field string, dst *string, v string) {
+		v = strings.TrimSpace(v)
+		switch {
+		case v == "":
+		case *dst == "":
+			*dst = v
+		case !strings.EqualFold(*dst, v):
+			issues = append(issues, MergeIssue{
+				Kind: IssueConflict, Stage: stage, Number: r.Number, Name: r.Name, Key: c.Key(),
+				Field: field, Kept: *dst, Dropped: v,
+			})
+		}
+	}
+	if r.Email != "" && r.EmailSource == "" && stage == "Procedure2" && IsLegacyGuess(r.Email, c.Name) {
+		r.GuessedEmail, r.Email = r.Email, ""
+	}
+	set(FieldLink2, &c.Link2, r.Link2)
+	set(FieldEmail, &c.Email, r.Email)
+	if c.Email == r.Email && c.EmailSource == "" {
+		c.EmailSource = r.EmailSource
+	}
+	if c.Email == "" {
+		set(FieldGuessed, &c.GuessedEmail, r.GuessedEmail)
+	}
+	set(FieldOutcome, &c.Outcome, r.Outcome)
+	set(FieldPass, &c.Pass, r.Pass)
+	set(FieldTLS, &c.TLS, r.TLS)
+	source := r.EmailSource
+	if source == "" {
+		source = EmailImported
+	}
+	c.AddCandidates(source, r.Email)
+	c.AddCandidates(EmailGuessed, r.GuessedEmail)
+	for _, cand := range r.Candidates {
+		c.AddCandidates(cand.Source, cand.Email)
+	}
+	if c.Email != "" {
+		c.GuessedEmail = ""
+	}
+	return issues
+}
+
+// normalizeName 比较公司名时只看小写的字母和数字
+func normalizeName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
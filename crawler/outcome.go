@@ -0,0 +1,46 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// 每行结果的代码：E开头计为失败，S开头为主动跳过（不计入失败）
+const (
+	OutcomeOK          = "OK"
+	OutcomeNoLink      = "E1001" // 没有官网链接
+	OutcomeFetchFailed = "E1002" // 请求失败（代理也失败）
+	OutcomeParseFailed = "E1003" // 页面解析失败
+	OutcomeNoEmail     = "E1004" // 页面中没有邮箱
+	OutcomeBadStatus   = "E1005" // 非200状态码
+	OutcomeNoProfile   = "E1006" // ENF详情页请求失败，不知道有没有官网
+	OutcomeRobots      = "S1001" // robots.txt 禁止抓取
+	OutcomeCacheMiss   = "S1002" // 离线模式下缓存中没有
+	OutcomeCanceled    = "S1003" // 退出时被中断
+)
+
+// IsFailure 是否计入失败
+func IsFailure(code string) bool {
+	return strings.HasPrefix(code, "E")
+}
+
+// IsSkipped 是否为主动跳过
+func IsSkipped(code string) bool {
+	return strings.HasPrefix(code, "S")
+}
+
+// OutcomeOf 把Fetch返回的错误转换成结果代码
+func OutcomeOf(err error) string {
+	switch {
+	case err == nil:
+		return OutcomeOK
+	case errors.Is(err, ErrRobotsDisallowed):
+		return OutcomeRobots
+	case errors.Is(err, ErrCacheMiss):
+		return OutcomeCacheMiss
+	case errors.Is(err, context.Canceled):
+		return OutcomeCanceled
+	}
+	return OutcomeFetchFailed
+}
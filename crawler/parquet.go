@@ -0,0 +1,58 @@
+package crawler
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// ParquetSink Parquet输出（见 Record）。行先在内存中攒成行组，Close 时写完并改名，
+// 运行中文件不可读。
+type ParquetSink struct {
+	mu   sync.Mutex
+	f    *AtomicFile
+	w    *parquet.GenericWriter[Record]
+	prov Provenance
+}
+
+// NewParquetSink 创建Parquet输出文件
+func NewParquetSink(path string, prov Provenance) (*ParquetSink, error) {
+	f, err := CreateAtomic(path)
+	if err != nil {
+		return nil, fmt.Errorf("无法创建输出Parquet：%v", err)
+	}
+	w := parquet.NewGenericWriter[Record](f, parquet.Compression(&parquet.Zstd))
+	return &ParquetSink{f: f, w: w, prov: prov}, nil
+}
+
+// SetSource 修改之后写出的记录的来源
+func (s *ParquetSink) SetSource(source string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prov.Source = source
+}
+
+func (s *ParquetSink) Write(c Company) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.w.Write([]Record{NewRecord(c, s.prov)})
+	return err
+}
+
+func (s *ParquetSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.w.Close(); err != nil {
+		s.f.Close()
+		return err
+	}
+	return s.f.Commit()
+}
+
+// Abort 删除临时文件，不生成目标文件
+func (s *ParquetSink) Abort() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.f.Close()
+}
@@ -0,0 +1,367 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// 列表页连续失败多少次后停止翻页
+const maxListingFailures = 3
+
+// Pipeline 进程内流水线：列表页 → ENF详情(Link2、详情页邮箱) → 官网邮箱 → 失败重试 → 猜测兜底 → 输出。
+// 各阶段之间用通道连接，一家公司读到后几分钟内即可完成全部处理。
+type Pipeline struct {
+	Category string // 见 CategoryNames
+	Country  string
+	MaxPages int // 最多抓取的列表页数，0 表示直到空页
+
+	ENF     *Fetcher // 列表页、详情页
+	Website *Fetcher // 公司官网
+	Retry   *Fetcher // 官网请求失败的记录最后用它再试一次，为nil时不重试
+
+	DetailWorkers  int
+	WebsiteWorkers int
+	PerDomain      int           // 同一注册域名同时抓取的官网数，0 表示不限制
+	PageDelay      time.Duration // 列表页之间的间隔，防止被限制
+
+	Sinks []Sink
+
+	// Stop 关闭后不再读取新的列表页，已读到的公司继续处理完
+	Stop <-chan struct{}
+}
+
+// Run 运行流水线直到所有记录写出，返回写出的记录数
+func (p *Pipeline) Run(ctx context.Context) (int, error) {
+	// 各阶段并发完成的顺序不定，按列表中的序号写出；留到重试轮的记录让出位置，重试完成后追加
+	count := 0
+	order := NewReorder(1, func(c Company) error {
+		count++
+		fmt.Printf("%d,%s,%s,%s,%s,%s,%s,%s,%s\n", c.Number, c.Name, c.Link2, c.Email, c.EmailSource, c.GuessedEmail, c.Outcome, c.Pass, c.TLS)
+		return writeSinks(p.Sinks, c)
+	})
+
+	listed := p.Listing(ctx)
+	detailed := runStage(listed, p.DetailWorkers, func(c Company) Company { return p.detail(ctx, c) })
+	enriched := runScheduledStage(detailed, p.WebsiteWorkers, p.PerDomain, websiteKey, func(c Company) Company {
+		return p.website(ctx, c)
+	})
+	retried := p.retryStage(ctx, enriched, func(c Company) { order.Skip(c.Number) })
+	guessed := runStage(retried, 1, guess)
+
+	for c := range guessed {
+		order.Put(c.Number, c)
+	}
+	// 中断时未完成的序号留下空缺
+	sinkErr := order.Flush()
+	for _, s := range p.Sinks {
+		if err := s.Close(); err != nil && sinkErr == nil {
+			sinkErr = err
+		}
+	}
+	return count, sinkErr
+}
+
+// writeSinks 写入所有输出端，返回第一个错误
+func writeSinks(sinks []Sink, c Company) error {
+	var first error
+	for _, s := range sinks {
+		if err := s.Write(c); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// runStage 启动workers个goroutine处理in中的记录，全部处理完后关闭输出通道
+func runStage(in <-chan Company, workers int, fn func(Company) Company) <-chan Company {
+	if workers < 1 {
+		workers = 1
+	}
+	out := make(chan Company, workers)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for c := range in {
+				out <- fn(c)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// runScheduledStage 与 runStage 相同，但同一key同时最多处理perKey条
+func runScheduledStage(in <-chan Company, workers, perKey int, key func(Company) string, fn func(Company) Company) <-chan Company {
+	out := make(chan Company, workers)
+	s := NewScheduler(workers, perKey, key, func(c Company) { out <- fn(c) })
+	go func() {
+		for c := range in {
+			s.Submit(c)
+		}
+		s.Wait()
+		close(out)
+	}()
+	return out
+}
+
+func websiteKey(c Company) string {
+	return RegisteredDomain(c.Link2)
+}
+
+// retryStage 官网请求失败（超时、代理失败）的记录先留下并调用 onDefer，其余记录照常往下传；
+// 第一轮全部结束后，用 Retry 抓取器以更长超时、其他代理和链接变体再试一次
+func (p *Pipeline) retryStage(ctx context.Context, in <-chan Company, onDefer func(Company)) <-chan Company {
+	if p.Retry == nil {
+		return in
+	}
+	out := make(chan Company, p.WebsiteWorkers)
+	go func() {
+		defer close(out)
+		var deferred []Company
+		for c := range in {
+			if RetryableOutcome(c.Outcome) {
+				deferred = append(deferred, c)
+				onDefer(c)
+				continue
+			}
+			out <- c
+		}
+		if len(deferred) == 0 {
+			return
+		}
+		select {
+		case <-p.Stop:
+			// 正在退出，不再重试，按第一轮结果写出
+			for _, c := range deferred {
+				out <- c
+			}
+			return
+		default:
+		}
+		log.Printf("第二轮：重试 %d 条官网请求失败的记录\n", len(deferred))
+		queue := make(chan Company)
+		go func() {
+			defer close(queue)
+			for _, c := range deferred {
+				queue <- c
+			}
+		}()
+		for c := range runScheduledStage(queue, p.WebsiteWorkers, p.PerDomain, websiteKey, func(c Company) Company {
+			return p.retry(ctx, c)
+		}) {
+			out <- c
+		}
+	}()
+	return out
+}
+
+// Listing 逐页读取目录列表，每读到一家公司立即交给下一阶段
+func (p *Pipeline) Listing(ctx context.Context) <-chan Company {
+	out := make(chan Company, p.DetailWorkers)
+	go func() {
+		defer close(out)
+		cat, err := LookupCategory(p.Category)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		number, failures := 0, 0
+		for page := 1; p.MaxPages <= 0 || page <= p.MaxPages; page++ {
+			select {
+			case <-p.Stop:
+				log.Printf("停止读取列表页（已读到第%d页）\n", page-1)
+				return
+			case <-ctx.Done():
+				return
+			default:
+			}
+			entries, err := FetchListing(ctx, p.ENF, cat, p.Country, page)
+			if err != nil {
+				log.Printf("抓取列表页失败 %s：%v\n", DirectoryURL(p.Category, p.Country, page), err)
+				if failures++; failures >= maxListingFailures {
+					return
+				}
+				continue
+			}
+			failures = 0
+			if len(entries) == 0 {
+				log.Printf("列表页 %d 没有公司，结束翻页\n", page)
+				return
+			}
+			for _, e := range entries {
+				number++
+				c := Company{
+					Category: p.Category,
+					Country:  p.Country,
+					Number:   number,
+					Name:     e.Name,
+					Address:  e.Address,
+					Link1:    e.Link1,
+					Details:  e.Details,
+				}
+				select {
+				case out <- c:
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case <-time.After(p.PageDelay):
+			case <-p.Stop:
+				log.Printf("停止读取列表页（已读到第%d页）\n", page)
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Enrich 对单条记录执行详情页和官网两个阶段；Pass 为 PassRetry 时按重试轮处理。
+// 分布式模式下worker用它处理领取到的任务。
+func (p *Pipeline) Enrich(ctx context.Context, c Company) Company {
+	if c.Pass == PassRetry {
+		return p.retry(ctx, c)
+	}
+	return p.website(ctx, p.detail(ctx, c))
+}
+
+// detail 从ENF详情页取官网链接和邮箱；请求失败时记下结果代码，website 不再处理
+func (p *Pipeline) detail(ctx context.Context, c Company) Company {
+	profile, err := FetchProfile(ctx, p.ENF, c.Link1)
+	if err != nil {
+		log.Printf("获取第%d条 %s 详情页失败：%v\n", c.Number, c.Name, err)
+		c.Outcome, c.TLS = OutcomeOf(err), TLSErrorOf(err)
+		if c.Outcome == OutcomeFetchFailed {
+			c.Outcome = OutcomeNoProfile
+		}
+		return c
+	}
+	c.Link2 = profile.Website
+	if c.Address == "" {
+		c.Address = profile.Address
+	}
+	if profile.Email != "" {
+		c.Email, c.EmailSource = profile.Email, EmailFromProfile
+		c.AddCandidates(EmailFromProfile, profile.Email)
+	}
+	return c
+}
+
+// website 详情页没有邮箱时，从公司官网提取
+func (p *Pipeline) website(ctx context.Context, c Company) Company {
+	c.Pass = PassMain
+	if c.Outcome != "" {
+		return c // 详情页请求失败
+	}
+	if c.Email != "" {
+		c.Outcome = OutcomeOK
+		return c
+	}
+	if c.Link2 == "" {
+		c.Outcome = OutcomeNoLink
+		return c
+	}
+	resp, err := p.Website.Fetch(ctx, c.Link2)
+	return fromWebsite(c, resp, err)
+}
+
+// retry 第二轮抓取官网；第一轮详情页请求失败的先重新抓取详情页
+func (p *Pipeline) retry(ctx context.Context, c Company) Company {
+	c.Pass = PassRetry
+	if c.Outcome == OutcomeNoProfile {
+		c.Outcome, c.TLS = "", ""
+		if c = p.detail(ctx, c); c.Outcome != "" {
+			return c
+		}
+		if c.Email != "" {
+			c.Outcome = OutcomeOK
+			return c
+		}
+		if c.Link2 == "" {
+			c.Outcome = OutcomeNoLink
+			return c
+		}
+	}
+	f := p.Retry
+	if f == nil {
+		f = p.Website
+	}
+	resp, err := FetchAlternates(ctx, f, c.Link2)
+	return fromWebsite(c, resp, err)
+}
+
+// fromWebsite 记下官网抓取的结果和跳转后的地址
+func fromWebsite(c Company, resp *Response, err error) Company {
+	emails, outcome, tlsClass := emailsFromResponse(resp, err)
+	c.Outcome, c.TLS = outcome, tlsClass
+	if resp != nil && resp.FinalURL != "" {
+		c.FinalURL = resp.FinalURL
+	}
+	if len(emails) > 0 {
+		c.Email, c.EmailSource = emails[0], EmailFromWebsite
+		c.AddCandidates(EmailFromWebsite, emails...)
+	}
+	return c
+}
+
+// guess 仍然没有邮箱时按官网（跳转后的）域名猜测，结果只放在 GuessedEmail
+func guess(c Company) Company {
+	if c.Email != "" {
+		return c
+	}
+	website := c.FinalURL
+	if website == "" {
+		website = c.Link2
+	}
+	guesses := GuessEmails(website, c.Country)
+	if len(guesses) > 0 {
+		c.GuessedEmail = guesses[0]
+		c.AddCandidates(EmailGuessed, guesses...)
+	}
+	return c
+}
+
+// WebsiteEmail 抓取公司官网并提取邮箱，返回邮箱、结果代码和TLS错误类别
+func WebsiteEmail(ctx context.Context, f *Fetcher, link string) (email, outcome, tlsClass string) {
+	if link == "" {
+		return "", OutcomeNoLink, ""
+	}
+	resp, err := f.Fetch(ctx, link)
+	emails, outcome, tlsClass := emailsFromResponse(resp, err)
+	if len(emails) > 0 {
+		email = emails[0]
+	}
+	return email, outcome, tlsClass
+}
+
+// emailsFromResponse 官网页面上的全部邮箱，第一个与 ExtractEmail 的结果相同
+func emailsFromResponse(resp *Response, err error) (emails []string, outcome, tlsClass string) {
+	if err != nil {
+		return nil, OutcomeOf(err), TLSErrorOf(err)
+	}
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(resp.Body))
+	if err != nil {
+		return nil, OutcomeParseFailed, resp.TLSError
+	}
+	emails = ExtractEmails(doc.Text())
+	switch {
+	case len(emails) > 0:
+		return emails, OutcomeOK, resp.TLSError
+	case resp.StatusCode == 200:
+		return nil, OutcomeNoEmail, resp.TLSError
+	}
+	return nil, OutcomeBadStatus, resp.TLSError
+}
@@ -0,0 +1,40 @@
+package crawler
+
+import "sync"
+
+// Pool 固定数量的worker从任务通道中取任务执行。
+// 通道缓冲只有worker数量大小，Submit在队列满时阻塞，
+// 生产者因此不会比消费者快太多，内存占用与输入大小无关。
+type Pool[J any] struct {
+	jobs chan J
+	wg   sync.WaitGroup
+}
+
+// NewPool 启动workers个worker，每个任务调用一次handle
+func NewPool[J any](workers int, handle func(J)) *Pool[J] {
+	if workers < 1 {
+		workers = 1
+	}
+	p := &Pool[J]{jobs: make(chan J, workers)}
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer p.wg.Done()
+			for job := range p.jobs {
+				handle(job)
+			}
+		}()
+	}
+	return p
+}
+
+// Submit 提交任务，队列满时阻塞
+func (p *Pool[J]) Submit(job J) {
+	p.jobs <- job
+}
+
+// Wait 不再接受新任务，等待已提交的任务全部完成
+func (p *Pool[J]) Wait() {
+	close(p.jobs)
+	p.wg.Wait()
+}
@@ -0,0 +1,262 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// EnfProfile ENF公司详情页上的信息
+type EnfProfile struct {
+	Name              string            `json:"name"`
+	Website           string            `json:"website"` // 官网，即 Link2
+	Email             string            `json:"email"`
+	Phone             string            `json:"phone"`
+	Address           string            `json:"address"` // 完整地址，一行
+	Street            string            `json:"street"`
+	City              string            `json:"city"`
+	Postcode          string            `json:"postcode"`
+	Region            string            `json:"region"`
+	Country           string            `json:"country"`
+	Products          []string          `json:"products"`           // 经营的产品类别，如 Solar Panels
+	Brands            []string          `json:"brands"`             // 经销或安装的品牌
+	InstallationSizes []string          `json:"installation_sizes"` // 安装规模，如 Residential、Commercial
+	ServiceArea       []string          `json:"service_area"`
+	Languages         []string          `json:"languages"`
+	Extra             map[string]string `json:"extra,omitempty"` // 没有对应字段的其它项，键为页面上的标签
+	Throttled         bool              `json:"throttled"`       // 页面上是并发限制的占位邮箱，Email 已清空
+}
+
+type profileField struct {
+	aliases []string // 小写，比较前标签也转小写、去掉冒号并合并空白
+	set     func(p *EnfProfile, text string, items []string)
+}
+
+// 详情页各项的标签，英文站和中文站都有
+var profileFields = []profileField{
+	{[]string{"telephone", "phone", "tel", "tel.", "电话", "联系电话"}, func(p *EnfProfile, text string, _ []string) {
+		p.Phone = text
+	}},
+	{[]string{"address", "地址"}, func(p *EnfProfile, text string, _ []string) { p.Address = text }},
+	{[]string{"email", "e-mail", "邮箱", "电子邮箱"}, func(*EnfProfile, string, []string) {}}, // 邮箱是编码的，见 ExtractProfileEmail
+	{[]string{"website", "官网", "网站"}, func(*EnfProfile, string, []string) {}},
+	{[]string{"products", "product", "main products", "business type", "产品", "主要产品", "业务类型"}, func(p *EnfProfile, _ string, items []string) {
+		p.Products = appendUnique(p.Products, items...)
+	}},
+	{[]string{"brands", "panel brands", "inverter brands", "storage brands", "panel suppliers", "inverter suppliers", "brands carried",
+		"品牌", "组件品牌", "逆变器品牌", "储能品牌", "经销品牌", "组件供应商", "逆变器供应商"}, func(p *EnfProfile, _ string, items []string) {
+		p.Brands = appendUnique(p.Brands, items...)
+	}},
+	{[]string{"installation size", "installation sizes", "installation type", "system size", "安装规模", "安装容量", "装机规模", "安装类型"}, func(p *EnfProfile, _ string, items []string) {
+		p.InstallationSizes = appendUnique(p.InstallationSizes, items...)
+	}},
+	{[]string{"service area", "service areas", "service coverage", "area served", "operating area", "服务区域", "服务范围", "服务地区"}, func(p *EnfProfile, _ string, items []string) {
+		p.ServiceArea = appendUnique(p.ServiceArea, items...)
+	}},
+	{[]string{"languages", "languages spoken", "language", "语言", "使用语言"}, func(p *EnfProfile, _ string, items []string) {
+		p.Languages = appendUnique(p.Languages, items...)
+	}},
+}
+
+var profileAliases = func() map[string]*profileField {
+	m := make(map[string]*profileField)
+	for i := range profileFields {
+		for _, alias := range profileFields[i].aliases {
+			m[alias] = &profileFields[i]
+		}
+	}
+	return m
+}()
+
+// ParseProfile 解析ENF详情页。名称、官网、电话、地址优先取 itemprop 标注的内容，
+// 其余各项按标签对应：表格的两列（th/td 或 td/td）、dl 的 dt/dd，
+// 以及 class 以 -title / -label 结尾的元素和紧跟着的同级元素。
+// 列表项优先取值中的 li / a / span，没有时按逗号、顿号、分号和换行拆分。
+func ParseProfile(body []byte) (EnfProfile, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return EnfProfile{}, fmt.Errorf("解析详情页失败: %v", err)
+	}
+	var p EnfProfile
+	p.Name = cleanText(doc.Find(`[itemprop="name"]`).First().Text())
+	if p.Name == "" {
+		p.Name = cleanText(doc.Find("h1").First().Text())
+	}
+	if href, ok := doc.Find(`a[itemprop="url"]`).Attr("href"); ok {
+		p.Website = strings.TrimSpace(href)
+	}
+	p.Email = ExtractProfileEmail(string(body))
+	if p.Email == ENFThrottleEmail {
+		p.Email, p.Throttled = "", true
+	}
+	p.Phone = cleanText(doc.Find(`[itemprop="telephone"]`).First().Text())
+	address := doc.Find(`[itemprop="address"]`).First()
+	p.Street = cleanText(address.Find(`[itemprop="streetAddress"]`).Text())
+	p.City = cleanText(address.Find(`[itemprop="addressLocality"]`).Text())
+	p.Postcode = cleanText(address.Find(`[itemprop="postalCode"]`).Text())
+	p.Region = cleanText(address.Find(`[itemprop="addressRegion"]`).Text())
+	p.Country = cleanText(address.Find(`[itemprop="addressCountry"]`).Text())
+	p.Address = joinAddress(address)
+
+	// extra 为 true 时没有对应字段的项记入 Extra
+	pair := func(label, value *goquery.Selection, extra bool) {
+		key := profileLabel(label.Text())
+		if key == "" || value.Length() == 0 {
+			return
+		}
+		text := cleanText(value.Text())
+		if text == "" {
+			return
+		}
+		f, ok := profileAliases[key]
+		if !ok {
+			if extra {
+				if p.Extra == nil {
+					p.Extra = make(map[string]string)
+				}
+				label := strings.TrimRight(cleanText(label.Text()), ":：")
+				if _, dup := p.Extra[label]; !dup {
+					p.Extra[label] = text
+				}
+			}
+			return
+		}
+		// itemprop 中已经有的不覆盖
+		if (f == profileAliases["telephone"] && p.Phone != "") || (f == profileAliases["address"] && p.Address != "") {
+			return
+		}
+		f.set(&p, text, listItems(value))
+	}
+	doc.Find("tr").Each(func(_ int, tr *goquery.Selection) {
+		cells := tr.ChildrenFiltered("th, td")
+		if cells.Length() == 2 {
+			pair(cells.Eq(0), cells.Eq(1), true)
+		}
+	})
+	doc.Find("dt").Each(func(_ int, dt *goquery.Selection) {
+		pair(dt, dt.NextFiltered("dd"), true)
+	})
+	doc.Find("[class]").Each(func(_ int, s *goquery.Selection) {
+		for _, class := range strings.Fields(s.AttrOr("class", "")) {
+			if strings.HasSuffix(class, "-title") || strings.HasSuffix(class, "-label") {
+				pair(s, s.Next(), false)
+				return
+			}
+		}
+	})
+	return p, nil
+}
+
+// FetchProfile 抓取并解析ENF公司详情页
+func FetchProfile(ctx context.Context, f *Fetcher, link1 string) (EnfProfile, error) {
+	resp, err := f.Fetch(ctx, ENFURL(link1))
+	if err != nil {
+		return EnfProfile{}, err
+	}
+	return ParseProfile(resp.Body)
+}
+
+// profileLabel 规范化标签：小写、去掉结尾的冒号、合并空白
+func profileLabel(s string) string {
+	s = strings.ToLower(cleanText(s))
+	return strings.TrimSpace(strings.TrimRight(s, ":："))
+}
+
+// cleanText 合并空白，去掉首尾空白
+func cleanText(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// joinAddress 把 itemprop="address" 中的各部分用逗号连成一行
+func joinAddress(address *goquery.Selection) string {
+	if address.Length() == 0 {
+		return ""
+	}
+	var parts []string
+	address.Find("[itemprop]").Each(func(_ int, s *goquery.Selection) {
+		if t := strings.Trim(cleanText(s.Text()), ", "); t != "" {
+			parts = append(parts, t)
+		}
+	})
+	if len(parts) == 0 {
+		return strings.Trim(cleanText(address.Text()), ", ")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// listItems 值中的列表项：li、a 或多个 span，没有时拆分文本
+func listItems(value *goquery.Selection) []string {
+	for _, sel := range []string{"li", "a", "span"} {
+		found := value.Find(sel)
+		min := 1
+		if sel == "span" {
+			min = 2 // 单个 span 多半只是包着整段文本
+		}
+		if found.Length() < min {
+			continue
+		}
+		var items []string
+		found.Each(func(_ int, s *goquery.Selection) {
+			// 嵌套的元素只取最外层
+			if s.ParentsFiltered(sel).Length() > value.ParentsFiltered(sel).Length() {
+				return
+			}
+			if t := cleanText(s.Text()); t != "" {
+				items = append(items, t)
+			}
+		})
+		if len(items) > 0 {
+			return items
+		}
+	}
+	var text strings.Builder
+	for _, n := range value.Nodes {
+		collectText(&text, n)
+	}
+	return splitItems(text.String())
+}
+
+// collectText 取节点文本，<br> 转为换行
+func collectText(b *strings.Builder, n *html.Node) {
+	switch {
+	case n.Type == html.TextNode:
+		b.WriteString(n.Data)
+	case n.Type == html.ElementNode && n.Data == "br":
+		b.WriteString("\n")
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		collectText(b, c)
+	}
+}
+
+func splitItems(s string) []string {
+	var items []string
+	for _, item := range strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == '，' || r == '、' || r == ';' || r == '；' || r == '\n' || r == '|'
+	}) {
+		if item = cleanText(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func appendUnique(list []string, items ...string) []string {
+	for _, item := range items {
+		dup := false
+		for _, existing := range list {
+			if strings.EqualFold(existing, item) {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			list = append(list, item)
+		}
+	}
+	return list
+}
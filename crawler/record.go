@@ -0,0 +1,95 @@
+package crawler
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Provenance 记录的来源：哪一次运行、从哪个输入得到
+type Provenance struct {
+	RunID  string // 见 Run.ID
+	Source string // 输入文件，或流水线抓取的ENF目录列表页
+}
+
+// Record JSONL / Parquet 输出的完整记录。与CSV不同，保留全部候选邮箱、
+// 结果代码的分类和来源信息，字段名在两种格式中相同。
+type Record struct {
+	Key          string            `json:"key" parquet:"key"`
+	Category     string            `json:"category" parquet:"category"`
+	Country      string            `json:"country" parquet:"country"`
+	Number       int64             `json:"number" parquet:"number"`
+	Name         string            `json:"name" parquet:"name"`
+	Address      string            `json:"address" parquet:"address"`
+	Link1        string            `json:"link1" parquet:"link1"`
+	Website      string            `json:"website" parquet:"website"`
+	FinalURL     string            `json:"final_url" parquet:"final_url"`
+	Email        string            `json:"email" parquet:"email"`
+	EmailSource  string            `json:"email_source" parquet:"email_source"`
+	GuessedEmail string            `json:"guessed_email" parquet:"guessed_email"`
+	Candidates   []EmailCandidate  `json:"candidates" parquet:"candidates,list"`
+	Outcome      string            `json:"outcome" parquet:"outcome"`
+	Failed       bool              `json:"failed" parquet:"failed"`
+	Skipped      bool              `json:"skipped" parquet:"skipped"`
+	Pass         string            `json:"pass" parquet:"pass"`
+	TLS          string            `json:"tls" parquet:"tls"`
+	Details      map[string]string `json:"details,omitempty" parquet:"details"` // 目录特有的列
+	RunID        string            `json:"run_id" parquet:"run_id"`
+	Source       string            `json:"source" parquet:"source"`
+	WrittenAt    time.Time         `json:"written_at" parquet:"written_at,timestamp(millisecond)"`
+}
+
+// NewRecord 把流水线记录转换为输出记录
+func NewRecord(c Company, prov Provenance) Record {
+	candidates := c.Candidates
+	if candidates == nil {
+		candidates = []EmailCandidate{} // JSON 中输出 [] 而不是 null
+	}
+	return Record{
+		Key:          c.Key(),
+		Category:     c.Category,
+		Country:      c.Country,
+		Number:       int64(c.Number),
+		Name:         c.Name,
+		Address:      c.Address,
+		Link1:        c.Link1,
+		Website:      c.Link2,
+		FinalURL:     c.FinalURL,
+		Email:        c.Email,
+		EmailSource:  c.EmailSource,
+		GuessedEmail: c.GuessedEmail,
+		Candidates:   candidates,
+		Outcome:      c.Outcome,
+		Failed:       IsFailure(c.Outcome),
+		Skipped:      IsSkipped(c.Outcome),
+		Pass:         c.Pass,
+		TLS:          c.TLS,
+		Details:      c.Details,
+		RunID:        prov.RunID,
+		Source:       prov.Source,
+		WrittenAt:    time.Now().UTC(),
+	}
+}
+
+// NewRecordSinks 按需创建与 csvPath 同名、扩展名为 .jsonl / .parquet 的输出，返回输出和文件路径
+func NewRecordSinks(csvPath string, prov Provenance, jsonl, parquet bool) ([]Sink, []string, error) {
+	base := strings.TrimSuffix(csvPath, filepath.Ext(csvPath))
+	var sinks []Sink
+	var paths []string
+	if jsonl {
+		s, err := NewJSONLSink(base+".jsonl", prov)
+		if err != nil {
+			return nil, nil, err
+		}
+		sinks, paths = append(sinks, s), append(paths, base+".jsonl")
+	}
+	if parquet {
+		s, err := NewParquetSink(base+".parquet", prov)
+		if err != nil {
+			AbortSinks(sinks...)
+			return nil, nil, err
+		}
+		sinks, paths = append(sinks, s), append(paths, base+".parquet")
+	}
+	return sinks, paths, nil
+}
@@ -0,0 +1,104 @@
+package crawler
+
+import (
+	"sort"
+	"sync"
+)
+
+// Reorder 按序号顺序输出乱序完成的记录：序号等于下一个待写序号时立即写出，
+// 并连带写出已经到达的后续记录；其余记录暂存，直到前面的空缺补上。
+// 运行中输出文件始终是有序的前缀。留到重试轮的记录用 Skip 让出位置，
+// 重试完成后追加在后面，不会让后续记录一直暂存。
+type Reorder[T any] struct {
+	mu      sync.Mutex
+	next    int
+	pending map[int]T
+	skipped map[int]bool
+	emit    func(T) error
+	err     error
+}
+
+// NewReorder 从序号 first 开始按顺序调用 emit
+func NewReorder[T any](first int, emit func(T) error) *Reorder[T] {
+	return &Reorder[T]{next: first, pending: make(map[int]T), skipped: make(map[int]bool), emit: emit}
+}
+
+// Skip 序号 seq 的记录稍后才会 Put（如留到重试轮），顺序写出时跳过它；
+// 之后 Put 的该记录在轮到时照常写出，已经越过时直接追加
+func (r *Reorder[T]) Skip(seq int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if seq < r.next {
+		return r.err
+	}
+	r.skipped[seq] = true
+	r.advance()
+	return r.err
+}
+
+// Put 放入序号为 seq 的记录，返回第一个写出错误
+func (r *Reorder[T]) Put(seq int, v T) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if seq < r.next {
+		// 已经越过的序号（如 Flush 之后）直接写出
+		r.write(v)
+		return r.err
+	}
+	r.pending[seq] = v
+	r.advance()
+	return r.err
+}
+
+// advance 写出从 next 开始连续到达或跳过的记录
+func (r *Reorder[T]) advance() {
+	for {
+		if v, ok := r.pending[r.next]; ok {
+			delete(r.pending, r.next)
+			delete(r.skipped, r.next)
+			r.next++
+			r.write(v)
+			continue
+		}
+		if !r.skipped[r.next] {
+			return
+		}
+		delete(r.skipped, r.next)
+		r.next++
+	}
+}
+
+// Pending 暂存中的记录数
+func (r *Reorder[T]) Pending() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.pending)
+}
+
+// Flush 跳过空缺，按序号写出所有暂存的记录。中断退出时使用。
+func (r *Reorder[T]) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	seqs := make([]int, 0, len(r.pending))
+	for seq := range r.pending {
+		seqs = append(seqs, seq)
+	}
+	sort.Ints(seqs)
+	for _, seq := range seqs {
+		r.write(r.pending[seq])
+		delete(r.pending, seq)
+		r.next = seq + 1
+	}
+	for seq := range r.skipped {
+		if seq < r.next {
+			delete(r.skipped, seq)
+		}
+	}
+	return r.err
+}
+
+func (r *Reorder[T]) write(v T) {
+	if err := r.emit(v); err != nil && r.err == nil {
+		r.err = err
+	}
+}
@@ -0,0 +1,81 @@
+package crawler
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// 结果由第几轮产生
+const (
+	PassMain  = "1" // 第一轮
+	PassRetry = "2" // 最后的重试轮
+)
+
+// Retryable 失败是否值得在最后重试一次：超时、连接失败、代理全部失败。
+// robots禁止、缓存未命中、主动中断都不重试。
+func Retryable(err error) bool {
+	return RetryableOutcome(OutcomeOf(err))
+}
+
+// RetryableOutcome 按结果代码判断是否可重试，ENF详情页请求失败的重试时从详情页开始
+func RetryableOutcome(code string) bool {
+	return code == OutcomeFetchFailed || code == OutcomeNoProfile
+}
+
+// RetryConfig 重试轮使用的参数：超时放大3倍，代理从下一个账号开始，
+// 不参与自适应并发统计（耗时本来就长，会把第一轮的并发拉低）
+func RetryConfig(cfg FetchConfig) FetchConfig {
+	cfg.Timeout *= 3
+	cfg.ProxyTimeout *= 3
+	cfg.ReadTimeout *= 3
+	cfg.ProxyOffset++
+	cfg.Limiter = nil
+	return cfg
+}
+
+// AlternateURLs 链接的其他写法：http/https 互换、加/去 www.
+func AlternateURLs(link string) []string {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || u.Host == "" {
+		return nil
+	}
+	toggleScheme := func(u url.URL) url.URL {
+		if u.Scheme == "https" {
+			u.Scheme = "http"
+		} else {
+			u.Scheme = "https"
+		}
+		return u
+	}
+	toggleWWW := func(u url.URL) url.URL {
+		if strings.HasPrefix(strings.ToLower(u.Host), "www.") {
+			u.Host = u.Host[4:]
+		} else {
+			u.Host = "www." + u.Host
+		}
+		return u
+	}
+	scheme := toggleScheme(*u)
+	if net.ParseIP(u.Hostname()) != nil {
+		return []string{scheme.String()}
+	}
+	www := toggleWWW(*u)
+	both := toggleWWW(scheme)
+	return []string{scheme.String(), www.String(), both.String()}
+}
+
+// FetchAlternates 重试轮：依次请求原链接和它的其他写法，
+// 直到成功或遇到不可重试的错误
+func FetchAlternates(ctx context.Context, f *Fetcher, link string) (*Response, error) {
+	var err error
+	for _, u := range append([]string{link}, AlternateURLs(link)...) {
+		var resp *Response
+		resp, err = f.Fetch(ctx, u)
+		if err == nil || !Retryable(err) {
+			return resp, err
+		}
+	}
+	return nil, err
+}
@@ -0,0 +1,262 @@
+package crawler
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RobotsAgent 匹配 robots.txt 中 User-agent 分组时使用的名称
+const RobotsAgent = "enf-crawler"
+
+// RobotsUserAgent 开启robots模式时请求使用的User-Agent，带上 RobotsAgent，站点按它写的规则才对得上
+const RobotsUserAgent = "Mozilla/5.0 (compatible; " + RobotsAgent + "/1.0)"
+
+// 单个Crawl-delay的上限，防止个别站点写一个极大的值卡住worker
+const maxCrawlDelay = 30 * time.Second
+
+// 下载robots.txt的超时，与触发下载的请求无关
+const robotsTimeout = 15 * time.Second
+
+var ErrRobotsDisallowed = errors.New("robots.txt 禁止抓取")
+
+type robotsRule struct {
+	allow bool
+	path  string
+	re    *regexp.Regexp
+}
+
+// newRobotsRule 把路径规则转换成正则，支持 * 通配和结尾的 $
+func newRobotsRule(allow bool, path string) robotsRule {
+	anchored := strings.HasSuffix(path, "$")
+	parts := strings.Split(strings.TrimSuffix(path, "$"), "*")
+	for i := range parts {
+		parts[i] = regexp.QuoteMeta(parts[i])
+	}
+	expr := "^" + strings.Join(parts, ".*")
+	if anchored {
+		expr += "$"
+	}
+	return robotsRule{allow: allow, path: path, re: regexp.MustCompile(expr)}
+}
+
+// robotsRules 某个host对我们生效的规则
+type robotsRules struct {
+	rules       []robotsRule
+	crawlDelay  time.Duration
+	disallowAll bool // robots.txt 返回5xx时按RFC 9309视为全部禁止
+}
+
+// allowed 按最长匹配原则判断路径是否允许，长度相同时Allow优先
+func (r *robotsRules) allowed(path string) bool {
+	if r.disallowAll {
+		return false
+	}
+	best, allow := -1, true
+	for _, rule := range r.rules {
+		if !rule.re.MatchString(path) {
+			continue
+		}
+		if len(rule.path) > best || (len(rule.path) == best && rule.allow) {
+			best, allow = len(rule.path), rule.allow
+		}
+	}
+	return allow
+}
+
+// parseRobots 解析robots.txt，取与agent匹配的分组，没有则取 * 分组
+func parseRobots(r io.Reader, agent string) *robotsRules {
+	agent = strings.ToLower(agent)
+	var (
+		specific, generic *robotsRules
+		current           []*robotsRules
+		inAgents          bool
+	)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		switch key {
+		case "user-agent":
+			if !inAgents {
+				current = nil
+			}
+			inAgents = true
+			name := strings.ToLower(value)
+			switch {
+			case name == "*":
+				if generic == nil {
+					generic = &robotsRules{}
+				}
+				current = append(current, generic)
+			case name != "" && strings.Contains(agent, name):
+				if specific == nil {
+					specific = &robotsRules{}
+				}
+				current = append(current, specific)
+			}
+		case "allow", "disallow":
+			inAgents = false
+			if value == "" {
+				continue // 空的Disallow表示全部允许
+			}
+			for _, g := range current {
+				g.rules = append(g.rules, newRobotsRule(key == "allow", value))
+			}
+		case "crawl-delay":
+			inAgents = false
+			secs, err := strconv.ParseFloat(value, 64)
+			if err != nil || secs <= 0 {
+				continue
+			}
+			delay := time.Duration(secs * float64(time.Second))
+			if delay > maxCrawlDelay {
+				delay = maxCrawlDelay
+			}
+			for _, g := range current {
+				g.crawlDelay = delay
+			}
+		default:
+			inAgents = false
+		}
+	}
+	if specific != nil {
+		return specific
+	}
+	if generic != nil {
+		return generic
+	}
+	return &robotsRules{}
+}
+
+type robotsEntry struct {
+	once  sync.Once
+	rules *robotsRules
+
+	mu   sync.Mutex
+	next time.Time // 按Crawl-delay下一次允许请求的时间
+}
+
+// RobotsCache 按host缓存robots.txt，同一个host只下载一次
+type RobotsCache struct {
+	client *http.Client
+	agent  string
+
+	mu      sync.Mutex
+	entries map[string]*robotsEntry
+}
+
+// NewRobotsCache 创建缓存，client 用于下载robots.txt
+func NewRobotsCache(client *http.Client) *RobotsCache {
+	return &RobotsCache{
+		client:  client,
+		agent:   RobotsAgent,
+		entries: make(map[string]*robotsEntry),
+	}
+}
+
+func (c *RobotsCache) entry(ctx context.Context, u *url.URL) *robotsEntry {
+	key := u.Scheme + "://" + u.Host
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	if !ok {
+		e = &robotsEntry{}
+		c.entries[key] = e
+	}
+	c.mu.Unlock()
+	e.once.Do(func() {
+		// 结果给同一host的所有请求共用，不能因为第一个请求被取消就缓存成没有限制
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), robotsTimeout)
+		defer cancel()
+		e.rules = c.download(ctx, key+"/robots.txt")
+	})
+	return e
+}
+
+// download 下载并解析robots.txt。
+// 4xx视为没有限制；5xx视为全部禁止；网络错误时不做限制，交给正式请求去报错。
+func (c *RobotsCache) download(ctx context.Context, robotsURL string) *robotsRules {
+	req, err := http.NewRequestWithContext(ctx, "GET", robotsURL, nil)
+	if err != nil {
+		return &robotsRules{}
+	}
+	req.Header.Set("User-Agent", RobotsUserAgent)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return &robotsRules{}
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 500:
+		return &robotsRules{disallowAll: true}
+	case resp.StatusCode != http.StatusOK:
+		return &robotsRules{}
+	}
+	return parseRobots(io.LimitReader(resp.Body, 512<<10), c.agent)
+}
+
+// Check 检查robots.txt是否允许抓取target，不等待Crawl-delay
+func (c *RobotsCache) Check(ctx context.Context, target string) error {
+	_, err := c.check(ctx, target)
+	return err
+}
+
+// Wait 检查robots.txt是否允许抓取target，允许时按Crawl-delay等待到可以请求为止
+func (c *RobotsCache) Wait(ctx context.Context, target string) error {
+	e, err := c.check(ctx, target)
+	if err != nil {
+		return err
+	}
+	if e.rules.crawlDelay <= 0 {
+		return nil
+	}
+	e.mu.Lock()
+	now := time.Now()
+	at := e.next
+	if at.Before(now) {
+		at = now
+	}
+	e.next = at.Add(e.rules.crawlDelay)
+	e.mu.Unlock()
+	select {
+	case <-time.After(time.Until(at)):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *RobotsCache) check(ctx context.Context, target string) (*robotsEntry, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	e := c.entry(ctx, u)
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	if !e.rules.allowed(path) {
+		return nil, ErrRobotsDisallowed
+	}
+	return e, nil
+}
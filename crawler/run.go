@@ -0,0 +1,193 @@
+package crawler
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LatestLink 输出根目录下指向最新一次运行的符号链接；不支持符号链接时为记录目录名的文本文件
+const LatestLink = "latest"
+
+// Run 一次运行的输出目录 <root>/<YYYYMMDD-HHMMSS>-<runID>/，
+// 目录中的 manifest.json 记录输入文件、参数和每个文件的统计，随处理进度更新。
+type Run struct {
+	ID      string
+	Root    string
+	Dir     string
+	Started time.Time
+
+	mu       sync.Mutex
+	manifest Manifest
+}
+
+// Manifest 运行记录
+type Manifest struct {
+	RunID       string            `json:"run_id"`
+	Command     string            `json:"command"`
+	Args        []string          `json:"args"`
+	Started     time.Time         `json:"started"`
+	Finished    *time.Time        `json:"finished,omitempty"`
+	Interrupted bool              `json:"interrupted"`
+	Settings    map[string]string `json:"settings"`
+	Inputs      []string          `json:"inputs"`
+	Files       []FileManifest    `json:"files"`
+}
+
+// FileManifest 单个输入的统计。同一批记录写成多种格式时只记一条，
+// 其他格式的文件列在 Outputs 中，汇总各条的 Rows / Failed 不会重复计算。
+type FileManifest struct {
+	Input    string         `json:"input,omitempty"`
+	Output   string         `json:"output"`
+	Outputs  []string       `json:"outputs,omitempty"` // 同一批记录的其他格式，如 JSONL、Parquet
+	Rows     int            `json:"rows"`
+	Failed   int            `json:"failed"`
+	Skipped  int            `json:"skipped"`
+	RetryOK  int            `json:"retry_ok"` // 第二轮成功的行数
+	Outcomes map[string]int `json:"outcomes"`
+}
+
+// Count 按结果代码统计一行
+func (fm *FileManifest) Count(outcome, pass string) {
+	if fm.Outcomes == nil {
+		fm.Outcomes = make(map[string]int)
+	}
+	fm.Rows++
+	fm.Outcomes[outcome]++
+	if IsFailure(outcome) {
+		fm.Failed++
+	}
+	if IsSkipped(outcome) {
+		fm.Skipped++
+	}
+	if outcome == OutcomeOK && pass == PassRetry {
+		fm.RetryOK++
+	}
+}
+
+// NewRun 在 root 下创建本次运行的目录，写入初始 manifest 并把 latest 指向它
+func NewRun(root string) (*Run, error) {
+	started := time.Now()
+	id := newLeaseID()[:6]
+	name := started.Format("20060102-150405") + "-" + id
+	dir := filepath.Join(root, name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建输出目录失败: %v", err)
+	}
+	r := &Run{
+		ID:      id,
+		Root:    root,
+		Dir:     dir,
+		Started: started,
+		manifest: Manifest{
+			RunID:    id,
+			Command:  filepath.Base(os.Args[0]),
+			Args:     os.Args[1:],
+			Started:  started,
+			Settings: make(map[string]string),
+		},
+	}
+	if err := r.save(); err != nil {
+		return nil, err
+	}
+	if err := updateLatest(root, name); err != nil {
+		return nil, fmt.Errorf("更新 %s 失败: %v", LatestLink, err)
+	}
+	return r, nil
+}
+
+// Date 运行日期，用于输出文件名
+func (r *Run) Date() string {
+	return r.Started.Format("20060102")
+}
+
+// Path 运行目录中的文件路径
+func (r *Run) Path(name string) string {
+	return filepath.Join(r.Dir, name)
+}
+
+// OutputPath 按输入文件生成输出路径 <type>_<country>_<stage><运行日期>.csv
+func (r *Run) OutputPath(in InputFile, stage string) string {
+	return r.Path(OutputName(in.Type, in.Country, stage, r.Date(), "csv"))
+}
+
+// SetFlags 把命令行参数（含默认值）记入 manifest
+func (r *Run) SetFlags(fs *flag.FlagSet) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fs.VisitAll(func(f *flag.Flag) {
+		r.manifest.Settings[f.Name] = f.Value.String()
+	})
+}
+
+// SetInputs 记录输入文件
+func (r *Run) SetInputs(paths ...string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.manifest.Inputs = paths
+	return r.save()
+}
+
+// AddFile 记录一个输入的统计，运行目录中的输出路径记为相对运行目录的文件名
+func (r *Run) AddFile(fm FileManifest) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fm.Output = r.relPath(fm.Output)
+	outputs := make([]string, len(fm.Outputs))
+	for i, path := range fm.Outputs {
+		outputs[i] = r.relPath(path)
+	}
+	if len(outputs) > 0 {
+		fm.Outputs = outputs
+	}
+	r.manifest.Files = append(r.manifest.Files, fm)
+	return r.save()
+}
+
+func (r *Run) relPath(path string) string {
+	if rel, err := filepath.Rel(r.Dir, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
+
+// Finish 记录结束时间和是否被中断
+func (r *Run) Finish(interrupted bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	r.manifest.Finished = &now
+	r.manifest.Interrupted = interrupted
+	return r.save()
+}
+
+func (r *Run) save() error {
+	data, err := json.MarshalIndent(r.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(r.Path("manifest.json"), append(data, '\n')); err != nil {
+		return fmt.Errorf("写入 manifest.json 失败: %v", err)
+	}
+	return nil
+}
+
+// updateLatest 原子地把 root/latest 指向 name：先建临时链接再改名覆盖
+func updateLatest(root, name string) error {
+	latest := filepath.Join(root, LatestLink)
+	tmp := latest + ".tmp"
+	os.Remove(tmp)
+	if err := os.Symlink(name, tmp); err == nil {
+		return os.Rename(tmp, latest)
+	}
+	// 不支持符号链接（如部分Windows环境）时写入目录名
+	if info, err := os.Lstat(latest); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		os.Remove(latest)
+	}
+	return writeFileAtomic(latest, []byte(name+"\n"))
+}
@@ -0,0 +1,172 @@
+package crawler
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// Scheduler 在worker池前面按key（通常是注册域名）限制同时进行的任务数。
+// 某个域名达到上限时，它的任务留在等待队列中，worker去处理其他域名的任务，
+// 一个慢站点不会占住大量worker。
+// 任务还可以按组（通常是输入文件）区分，各组轮流取任务，共享同一批worker。
+type Scheduler[J any] struct {
+	group  func(J) string
+	key    func(J) string
+	perKey int
+	limit  int // 每组等待队列上限，超过后该组的 Submit 阻塞
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	pending  map[string][]J
+	groups   []string // 有等待任务的组，按轮转顺序
+	cursor   int
+	inFlight map[string]int
+	closed   bool
+
+	jobs chan J
+	wg   sync.WaitGroup
+}
+
+// NewScheduler 启动workers个worker；perKey<=0 表示不限制单个key
+func NewScheduler[J any](workers, perKey int, key func(J) string, handle func(J)) *Scheduler[J] {
+	return NewGroupScheduler(workers, perKey, func(J) string { return "" }, key, handle)
+}
+
+// NewGroupScheduler 与 NewScheduler 相同，但按 group 分组轮流调度，
+// 小文件不必排在大文件后面
+func NewGroupScheduler[J any](workers, perKey int, group, key func(J) string, handle func(J)) *Scheduler[J] {
+	if workers < 1 {
+		workers = 1
+	}
+	s := &Scheduler[J]{
+		group:    group,
+		key:      key,
+		perKey:   perKey,
+		limit:    max(workers*4, 256),
+		pending:  make(map[string][]J),
+		inFlight: make(map[string]int),
+		jobs:     make(chan J),
+	}
+	s.cond = sync.NewCond(&s.mu)
+	s.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer s.wg.Done()
+			for job := range s.jobs {
+				handle(job)
+				s.done(s.key(job))
+			}
+		}()
+	}
+	go s.dispatch()
+	return s
+}
+
+// Submit 提交任务，所在组的等待队列满时阻塞
+func (s *Scheduler[J]) Submit(job J) {
+	g := s.group(job)
+	s.mu.Lock()
+	for len(s.pending[g]) >= s.limit {
+		s.cond.Wait()
+	}
+	if len(s.pending[g]) == 0 {
+		s.groups = append(s.groups, g)
+	}
+	s.pending[g] = append(s.pending[g], job)
+	s.mu.Unlock()
+	s.cond.Broadcast()
+}
+
+// Wait 不再接受新任务，等待全部任务完成
+func (s *Scheduler[J]) Wait() {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	s.cond.Broadcast()
+	s.wg.Wait()
+}
+
+func (s *Scheduler[J]) done(key string) {
+	s.mu.Lock()
+	s.inFlight[key]--
+	if s.inFlight[key] <= 0 {
+		delete(s.inFlight, key)
+	}
+	s.mu.Unlock()
+	s.cond.Broadcast()
+}
+
+// dispatch 各组轮流，组内按提交顺序取第一个未达上限的任务交给空闲worker
+func (s *Scheduler[J]) dispatch() {
+	defer close(s.jobs)
+	for {
+		s.mu.Lock()
+		var (
+			job J
+			ok  bool
+		)
+		for {
+			job, ok = s.next()
+			if ok || (s.closed && len(s.groups) == 0) {
+				break
+			}
+			s.cond.Wait()
+		}
+		if !ok {
+			s.mu.Unlock()
+			return
+		}
+		s.inFlight[s.key(job)]++
+		s.mu.Unlock()
+		s.cond.Broadcast() // 唤醒等待队列空位的 Submit
+		s.jobs <- job
+	}
+}
+
+// next 从cursor所在的组开始找可以执行的任务并移出等待队列
+func (s *Scheduler[J]) next() (J, bool) {
+	for n := 0; n < len(s.groups); n++ {
+		gi := (s.cursor + n) % len(s.groups)
+		g := s.groups[gi]
+		queue := s.pending[g]
+		for i, job := range queue {
+			if s.perKey > 0 && s.inFlight[s.key(job)] >= s.perKey {
+				continue
+			}
+			queue = append(queue[:i], queue[i+1:]...)
+			if len(queue) == 0 {
+				delete(s.pending, g)
+				s.groups = append(s.groups[:gi], s.groups[gi+1:]...)
+				s.cursor = gi
+			} else {
+				s.pending[g] = queue
+				s.cursor = gi + 1
+			}
+			if len(s.groups) > 0 {
+				s.cursor %= len(s.groups)
+			} else {
+				s.cursor = 0
+			}
+			return job, true
+		}
+	}
+	var zero J
+	return zero, false
+}
+
+// RegisteredDomain 返回链接的注册域名，如 https://shop.example.co.uk/x → example.co.uk
+func RegisteredDomain(link string) string {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || u.Hostname() == "" {
+		return link
+	}
+	host := strings.ToLower(u.Hostname())
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return domain
+}
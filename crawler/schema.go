@@ -0,0 +1,280 @@
+package crawler
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// 规范列名，与流水线输出的 CSVHeader 一致
+const (
+	FieldNumber      = "Number"
+	FieldCountry     = "Country"
+	FieldName        = "Company Name"
+	FieldAddress     = "Address"
+	FieldLink1       = "Link1"
+	FieldLink2       = "Company Website"
+	FieldEmail       = "Email"
+	FieldEmailSource = "Email Source"
+	FieldGuessed     = "Guessed Email"
+	FieldOutcome     = "Outcome"
+	FieldPass        = "Pass"
+	FieldTLS         = "TLS"
+)
+
+type field struct {
+	name    string
+	aliases []string // 小写，比较前表头也转小写并合并空白
+	set     func(c *Company, v string) error
+}
+
+// 各代CSV的列名：
+//   - Company/*_Company*.csv：Number, Company Name, Address, Link1, Link2
+//   - installer procedure1/2：Number, Company Name, (Company Website, Email 顺序不定)
+//   - seller procedure1/2：Number, Company Name, Email, Website —— 这里的 Website 是ENF详情页，即 Link1
+//   - 流水线输出：CSVHeader
+var fields = []field{
+	{FieldNumber, []string{"number", "no", "no.", "#", "序号"}, func(c *Company, v string) error {
+		if v == "" {
+			return nil
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("Number 不是整数: %q", v)
+		}
+		c.Number = n
+		return nil
+	}},
+	{FieldCountry, []string{"country", "国家"}, func(c *Company, v string) error { c.Country = v; return nil }},
+	{FieldName, []string{"company name", "company", "name", "公司名称"}, func(c *Company, v string) error { c.Name = v; return nil }},
+	{FieldAddress, []string{"address", "地址"}, func(c *Company, v string) error { c.Address = v; return nil }},
+	{FieldLink1, []string{"link1", "website", "enf link", "profile"}, func(c *Company, v string) error { c.Link1 = v; return nil }},
+	{FieldLink2, []string{"company website", "link2", "官网"}, func(c *Company, v string) error { c.Link2 = v; return nil }},
+	{FieldEmail, []string{"email", "e-mail", "邮箱"}, func(c *Company, v string) error { c.Email = v; return nil }},
+	{FieldEmailSource, []string{"email source"}, func(c *Company, v string) error { c.EmailSource = v; return nil }},
+	{FieldGuessed, []string{"guessed email"}, func(c *Company, v string) error { c.GuessedEmail = v; return nil }},
+	{FieldOutcome, []string{"outcome"}, func(c *Company, v string) error { c.Outcome = v; return nil }},
+	{FieldPass, []string{"pass"}, func(c *Company, v string) error { c.Pass = v; return nil }},
+	{FieldTLS, []string{"tls"}, func(c *Company, v string) error { c.TLS = v; return nil }},
+}
+
+func normalizeHeader(h string) string {
+	h = strings.TrimPrefix(h, "\ufeff") // Excel导出的UTF-8 BOM
+	return strings.ToLower(strings.Join(strings.Fields(h), " "))
+}
+
+// Mapping 按表头解析出的列位置
+type Mapping struct {
+	Header  []string
+	Missing []string // 要求但没有找到的列
+	Extra   []string // 无法识别的列，读取时忽略
+	index   map[string]int
+	details map[string]int // 目录特有的列（见 Category.Columns）→ 列位置
+}
+
+// MapHeader 按列名（含别名）匹配表头；required 中的列缺失时返回错误，Mapping 仍然可用于报告
+func MapHeader(header []string, required ...string) (*Mapping, error) {
+	m := &Mapping{Header: header, index: make(map[string]int)}
+	byAlias := make(map[string]string)
+	for _, f := range fields {
+		byAlias[normalizeHeader(f.name)] = f.name
+		for _, a := range f.aliases {
+			byAlias[a] = f.name
+		}
+	}
+	details := detailColumns()
+	for i, h := range header {
+		name, ok := byAlias[normalizeHeader(h)]
+		if !ok {
+			if detail, ok := details[normalizeHeader(h)]; ok {
+				if m.details == nil {
+					m.details = make(map[string]int)
+				}
+				m.details[detail] = i
+				continue
+			}
+			m.Extra = append(m.Extra, h)
+			continue
+		}
+		if _, dup := m.index[name]; dup {
+			m.Extra = append(m.Extra, h) // 同一字段出现两次时用第一列
+			continue
+		}
+		m.index[name] = i
+	}
+	for _, name := range required {
+		if !m.Has(name) {
+			m.Missing = append(m.Missing, name)
+		}
+	}
+	if len(m.Missing) > 0 {
+		return m, fmt.Errorf("缺少列: %s", strings.Join(m.Missing, ", "))
+	}
+	return m, nil
+}
+
+// Has 表头中是否有该列
+func (m *Mapping) Has(name string) bool {
+	_, ok := m.index[name]
+	return ok
+}
+
+// Index 列位置，没有该列时返回-1
+func (m *Mapping) Index(name string) int {
+	if i, ok := m.index[name]; ok {
+		return i
+	}
+	return -1
+}
+
+// Get 取一行中的某列，没有该列或该行较短时返回空串
+func (m *Mapping) Get(row []string, name string) string {
+	i := m.Index(name)
+	if i < 0 || i >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[i])
+}
+
+// Company 把一行转换为 Company；行太短缺少已匹配的列或 Number 不是整数时返回错误
+func (m *Mapping) Company(row []string) (Company, error) {
+	var c Company
+	for _, f := range fields {
+		i := m.Index(f.name)
+		if i < 0 {
+			continue
+		}
+		if i >= len(row) {
+			return c, fmt.Errorf("只有 %d 列，缺少 %s", len(row), f.name)
+		}
+		if err := f.set(&c, strings.TrimSpace(row[i])); err != nil {
+			return c, err
+		}
+	}
+	for name, i := range m.details {
+		if i < len(row) && strings.TrimSpace(row[i]) != "" {
+			if c.Details == nil {
+				c.Details = make(map[string]string)
+			}
+			c.Details[name] = strings.TrimSpace(row[i])
+		}
+	}
+	// 旧版流水线输出中猜测的邮箱和抓取到的混在 Email 列里
+	if c.EmailSource == EmailGuessed {
+		if c.GuessedEmail == "" {
+			c.GuessedEmail = c.Email
+		}
+		c.Email, c.EmailSource = "", ""
+	}
+	return c, nil
+}
+
+// Report 缺少或无法识别的列，供日志输出；都没有时返回空串
+func (m *Mapping) Report() string {
+	var parts []string
+	if len(m.Missing) > 0 {
+		parts = append(parts, "缺少列: "+strings.Join(m.Missing, ", "))
+	}
+	if len(m.Extra) > 0 {
+		parts = append(parts, "忽略未知列: "+strings.Join(m.Extra, ", "))
+	}
+	return strings.Join(parts, "；")
+}
+
+// CSVReader 按表头读取任意一代CSV
+type CSVReader struct {
+	Mapping *Mapping
+	r       *csv.Reader
+	line    int
+}
+
+// NewCSVReader 读取表头并匹配列
+func NewCSVReader(r io.Reader, required ...string) (*CSVReader, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1 // 列数不一致的行由 Mapping 报告
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("读取表头失败: %v", err)
+	}
+	m, err := MapHeader(header, required...)
+	if err != nil {
+		return nil, err
+	}
+	return &CSVReader{Mapping: m, r: cr, line: 1}, nil
+}
+
+// Read 读取下一行，同时返回原始列。io.EOF 表示结束；
+// 单行转换失败时返回 *RowError，可以跳过该行继续读取。
+func (r *CSVReader) Read() (Company, []string, error) {
+	row, err := r.r.Read()
+	if err != nil {
+		return Company{}, nil, err
+	}
+	r.line++
+	c, err := r.Mapping.Company(row)
+	if err != nil {
+		return c, row, &RowError{Line: r.line, Err: err}
+	}
+	return c, row, nil
+}
+
+// RowError 某一行无法转换
+type RowError struct {
+	Line int
+	Err  error
+}
+
+func (e *RowError) Error() string { return fmt.Sprintf("第%d行: %v", e.Line, e.Err) }
+
+func (e *RowError) Unwrap() error { return e.Err }
+
+// LoadCompanies 读取整个CSV文件，无法转换的行跳过并记录在 skipped 中
+func LoadCompanies(path string, required ...string) (companies []Company, m *Mapping, skipped []error, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("无法打开CSV文件 %s: %v", path, err)
+	}
+	defer f.Close()
+	r, err := NewCSVReader(f, required...)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("%s: %v", path, err)
+	}
+	for {
+		c, _, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		var rowErr *RowError
+		if errors.As(err, &rowErr) {
+			skipped = append(skipped, rowErr)
+			continue
+		}
+		if err != nil {
+			return companies, r.Mapping, skipped, fmt.Errorf("读取CSV失败 %s: %v", path, err)
+		}
+		companies = append(companies, c)
+	}
+	return companies, r.Mapping, skipped, nil
+}
+
+// CompanyWebsites 从 dir 中同一类型、国家的 Company 文件（见 FindCompanyFile）读取 Number → 官网（Link2）。
+// seller 各阶段的文件里只有ENF详情页，官网要从这里补。
+func CompanyWebsites(in InputFile, dir string) (map[int]string, error) {
+	file, err := FindCompanyFile(in, dir)
+	if err != nil {
+		return nil, err
+	}
+	companies, _, _, err := LoadCompanies(file.Path, FieldNumber)
+	if err != nil {
+		return nil, err
+	}
+	websites := make(map[int]string, len(companies))
+	for _, c := range companies {
+		websites[c.Number] = c.Link2
+	}
+	return websites, nil
+}
@@ -0,0 +1,84 @@
+package crawler
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Shutdown 捕获 SIGINT/SIGTERM 实现优雅退出：
+// 收到信号后 Stopping() 关闭，调用方不再提交新任务；
+// 宽限期过后 Context() 被取消，仍未完成的请求随之中断。
+// 再按一次 Ctrl-C 立即取消。
+type Shutdown struct {
+	stopping chan struct{}
+	once     sync.Once
+	ctx      context.Context
+	cancel   context.CancelFunc
+	signals  chan os.Signal
+}
+
+// NotifyShutdown 开始监听信号，grace 为进行中请求的宽限期
+func NotifyShutdown(grace time.Duration) *Shutdown {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Shutdown{
+		stopping: make(chan struct{}),
+		ctx:      ctx,
+		cancel:   cancel,
+		signals:  make(chan os.Signal, 2),
+	}
+	signal.Notify(s.signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		for range s.signals {
+			select {
+			case <-s.stopping:
+				log.Println("再次收到退出信号，立即中断")
+				cancel()
+				return
+			default:
+			}
+			log.Printf("收到退出信号，停止接收新任务，等待进行中的请求（最多%v）...\n", grace)
+			s.once.Do(func() { close(s.stopping) })
+			time.AfterFunc(grace, cancel)
+		}
+	}()
+	return s
+}
+
+// Context 请求使用的context，宽限期结束后取消
+func (s *Shutdown) Context() context.Context {
+	return s.ctx
+}
+
+// Stopping 收到退出信号后关闭
+func (s *Shutdown) Stopping() <-chan struct{} {
+	return s.stopping
+}
+
+// Interrupted 是否已收到退出信号
+func (s *Shutdown) Interrupted() bool {
+	select {
+	case <-s.stopping:
+		return true
+	default:
+		return false
+	}
+}
+
+// Stop 停止监听信号
+func (s *Shutdown) Stop() {
+	signal.Stop(s.signals)
+	s.cancel()
+}
+
+// IncompletePath 中断时的输出文件名：a/b.csv → a/b_INCOMPLETE.csv
+func IncompletePath(path string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "_INCOMPLETE" + ext
+}
@@ -0,0 +1,171 @@
+package crawler
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// Sink 流水线的输出端
+type Sink interface {
+	Write(c Company) error
+	Close() error
+}
+
+// AbortSinks 放弃写入：写文件的输出删除临时文件，不改名为目标文件，其余不做处理。
+// 用于创建输出途中出错时，避免把空的或不完整的文件当作结果留下。
+func AbortSinks(sinks ...Sink) {
+	for _, s := range sinks {
+		if a, ok := s.(interface{ Abort() }); ok {
+			a.Abort()
+		}
+	}
+}
+
+// CSVHeader 流水线CSV输出的列
+var CSVHeader = []string{FieldNumber, FieldCountry, FieldName, FieldAddress, FieldLink1, FieldLink2, FieldEmail, FieldEmailSource, FieldGuessed, FieldOutcome, FieldPass, FieldTLS}
+
+// CSVRow 把记录转换成CSV的一行，列顺序与 CSVHeader 一致
+func CSVRow(c Company) []string {
+	return []string{strconv.Itoa(c.Number), c.Country, c.Name, c.Address, c.Link1, c.Link2, c.Email, c.EmailSource, c.GuessedEmail, c.Outcome, c.Pass, c.TLS}
+}
+
+// CSVDetails 目录特有列的值，列顺序与 columns 一致
+func CSVDetails(c Company, columns []string) []string {
+	values := make([]string, len(columns))
+	for i, name := range columns {
+		values[i] = c.Details[name]
+	}
+	return values
+}
+
+// CSVSink 写入同目录下的临时文件，每写一行立即刷新，运行中也能查看已完成的记录；
+// Close 时 fsync 并改名为目标文件，中途崩溃不会留下半个输出文件。
+type CSVSink struct {
+	mu      sync.Mutex
+	f       *AtomicFile
+	w       *csv.Writer
+	details []string
+}
+
+// NewCSVSink 创建CSV输出文件并写入表头；details 为目录特有的列（见 Category.ColumnNames），排在 CSVHeader 之后
+func NewCSVSink(path string, details ...string) (*CSVSink, error) {
+	f, err := CreateAtomic(path)
+	if err != nil {
+		return nil, fmt.Errorf("无法创建输出CSV：%v", err)
+	}
+	s := &CSVSink{f: f, w: csv.NewWriter(f), details: details}
+	header := append(append([]string(nil), CSVHeader...), details...)
+	if err := s.w.Write(header); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *CSVSink) Write(c Company) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.w.Write(append(CSVRow(c), CSVDetails(c, s.details)...)); err != nil {
+		return err
+	}
+	s.w.Flush()
+	return s.w.Error()
+}
+
+func (s *CSVSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.w.Flush()
+	if err := s.w.Error(); err != nil {
+		s.f.Close()
+		return err
+	}
+	return s.f.Commit()
+}
+
+// Abort 删除临时文件，不生成目标文件
+func (s *CSVSink) Abort() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.f.Close()
+}
+
+// TallySink 只统计写出的行，用于 manifest
+type TallySink struct {
+	mu    sync.Mutex
+	stats FileManifest
+}
+
+func (t *TallySink) Write(c Company) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stats.Count(c.Outcome, c.Pass)
+	return nil
+}
+
+func (t *TallySink) Close() error { return nil }
+
+// Stats 目前为止的统计
+func (t *TallySink) Stats() FileManifest {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.stats
+}
+
+// JSONLSink 每行一个JSON对象（见 Record），每写一行立即刷新；与 CSVSink 一样写完后改名
+type JSONLSink struct {
+	mu   sync.Mutex
+	f    *AtomicFile
+	w    *bufio.Writer
+	enc  *json.Encoder
+	prov Provenance
+}
+
+// NewJSONLSink 创建JSON Lines输出文件
+func NewJSONLSink(path string, prov Provenance) (*JSONLSink, error) {
+	f, err := CreateAtomic(path)
+	if err != nil {
+		return nil, fmt.Errorf("无法创建输出JSONL：%v", err)
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return &JSONLSink{f: f, w: w, enc: enc, prov: prov}, nil
+}
+
+// SetSource 修改之后写出的记录的来源
+func (s *JSONLSink) SetSource(source string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prov.Source = source
+}
+
+func (s *JSONLSink) Write(c Company) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.enc.Encode(NewRecord(c, s.prov)); err != nil {
+		return err
+	}
+	return s.w.Flush()
+}
+
+func (s *JSONLSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.w.Flush(); err != nil {
+		s.f.Close()
+		return err
+	}
+	return s.f.Commit()
+}
+
+// Abort 删除临时文件，不生成目标文件
+func (s *JSONLSink) Abort() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.f.Close()
+}
@@ -0,0 +1,349 @@
+package crawler
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	_ "modernc.org/sqlite" // 纯Go的SQLite驱动，不需要CGO
+)
+
+// DefaultStorePath 各命令默认使用的数据库文件
+const DefaultStorePath = "crawler.db"
+
+// 表结构。时间均为UTC的RFC3339字符串。
+//   - companies：每个目录、国家中的一家公司，按 Company.Key 去重
+//   - profiles：ENF详情页上看到的官网和邮箱，同样的内容只记一次
+//   - fetches：每次抓取官网的结果，保留全部历史
+//   - emails：候选邮箱及来源（profile / website / guess / import），记录首次和最近一次看到的时间
+const storeSchema = `
+CREATE TABLE IF NOT EXISTS companies (
+	id         INTEGER PRIMARY KEY,
+	category   TEXT NOT NULL,
+	country    TEXT NOT NULL,
+	key        TEXT NOT NULL,
+	number     INTEGER NOT NULL DEFAULT 0,
+	name       TEXT NOT NULL DEFAULT '',
+	address    TEXT NOT NULL DEFAULT '',
+	link1      TEXT NOT NULL DEFAULT '',
+	link2      TEXT NOT NULL DEFAULT '',
+	created_at TEXT NOT NULL,
+	updated_at TEXT NOT NULL,
+	UNIQUE (category, country, key)
+);
+CREATE INDEX IF NOT EXISTS companies_number ON companies (category, country, number);
+CREATE TABLE IF NOT EXISTS profiles (
+	id         INTEGER PRIMARY KEY,
+	company_id INTEGER NOT NULL REFERENCES companies (id),
+	link1      TEXT NOT NULL,
+	website    TEXT NOT NULL,
+	email      TEXT NOT NULL,
+	fetched_at TEXT NOT NULL,
+	UNIQUE (company_id, link1, website, email)
+);
+CREATE TABLE IF NOT EXISTS fetches (
+	id         INTEGER PRIMARY KEY,
+	company_id INTEGER NOT NULL REFERENCES companies (id),
+	url        TEXT NOT NULL,
+	final_url  TEXT NOT NULL,
+	pass       TEXT NOT NULL,
+	outcome    TEXT NOT NULL,
+	tls        TEXT NOT NULL,
+	fetched_at TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS fetches_company ON fetches (company_id, id);
+CREATE TABLE IF NOT EXISTS emails (
+	company_id INTEGER NOT NULL REFERENCES companies (id),
+	email      TEXT NOT NULL,
+	source     TEXT NOT NULL,
+	first_seen TEXT NOT NULL,
+	last_seen  TEXT NOT NULL,
+	PRIMARY KEY (company_id, email, source)
+);
+`
+
+// EmailImported 从旧CSV导入、不知道来源的邮箱
+const EmailImported = "import"
+
+// Store 嵌入式数据库，保存公司、ENF详情页、官网抓取记录和候选邮箱。
+// 各阶段把结果写入这里，CSV/XLSX 只是导出格式。可以被多个goroutine同时使用。
+type Store struct {
+	db *sql.DB
+	mu sync.Mutex // 写入串行，避免 SQLITE_BUSY
+}
+
+// OpenStore 打开（必要时创建）数据库文件
+func OpenStore(path string) (*Store, error) {
+	dsn := "file:" + (&url.URL{Path: path}).EscapedPath() +
+		"?_pragma=busy_timeout(10000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)&_pragma=synchronous(NORMAL)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("打开数据库 %s 失败: %v", path, err)
+	}
+	if _, err := db.Exec(storeSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("初始化数据库 %s 失败: %v", path, err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Close 关闭数据库
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+func storeTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// Save 在一个事务中保存记录：更新公司信息（空字段不覆盖已有值），
+// 并按记录中的内容追加详情页、官网抓取结果和邮箱
+func (s *Store) Save(companies ...Company) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	now := storeTime(time.Now())
+	for _, c := range companies {
+		if err := saveCompany(tx, c, now); err != nil {
+			return fmt.Errorf("保存第%d条 %s 失败: %v", c.Number, c.Name, err)
+		}
+	}
+	return tx.Commit()
+}
+
+func saveCompany(tx *sql.Tx, c Company, now string) error {
+	id, err := upsertCompany(tx, c, now)
+	if err != nil {
+		return err
+	}
+	if c.Link1 != "" && (c.Link2 != "" || c.EmailSource == EmailFromProfile) {
+		email := ""
+		if c.EmailSource == EmailFromProfile {
+			email = c.Email
+		}
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO profiles (company_id, link1, website, email, fetched_at) VALUES (?, ?, ?, ?, ?)`,
+			id, c.Link1, c.Link2, email, now); err != nil {
+			return err
+		}
+	}
+	if c.Outcome != "" {
+		if _, err := tx.Exec(`INSERT INTO fetches (company_id, url, final_url, pass, outcome, tls, fetched_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			id, c.Link2, c.FinalURL, c.Pass, c.Outcome, c.TLS, now); err != nil {
+			return err
+		}
+	}
+	if c.Email != "" {
+		source := c.EmailSource
+		if source == "" {
+			source = EmailImported
+		}
+		if err := addEmail(tx, id, c.Email, source, now); err != nil {
+			return err
+		}
+	}
+	if c.GuessedEmail != "" {
+		if err := addEmail(tx, id, c.GuessedEmail, EmailGuessed, now); err != nil {
+			return err
+		}
+	}
+	for _, cand := range c.Candidates {
+		if err := addEmail(tx, id, cand.Email, cand.Source, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// upsertCompany 按 Key 找到或新建公司。没有ENF链接的记录（如 installer Procedure1）按 Number 匹配已有的公司；
+// 之前只按 Number 保存的公司在拿到ENF链接后改用ENF的标识。
+func upsertCompany(tx *sql.Tx, c Company, now string) (int64, error) {
+	key := c.Key()
+	var id int64
+	err := tx.QueryRow(`SELECT id FROM companies WHERE category = ? AND country = ? AND key = ?`, c.Category, c.Country, key).Scan(&id)
+	if err == sql.ErrNoRows && c.Number > 0 {
+		query := `SELECT id FROM companies WHERE category = ? AND country = ? AND number = ? ORDER BY updated_at DESC LIMIT 1`
+		if !strings.HasPrefix(key, "n:") {
+			query = `SELECT id FROM companies WHERE category = ? AND country = ? AND number = ? AND key LIKE 'n:%' LIMIT 1`
+		}
+		err = tx.QueryRow(query, c.Category, c.Country, c.Number).Scan(&id)
+		if err == nil && !strings.HasPrefix(key, "n:") {
+			if _, err := tx.Exec(`UPDATE companies SET key = ? WHERE id = ?`, key, id); err != nil {
+				return 0, err
+			}
+		}
+	}
+	switch {
+	case err == sql.ErrNoRows:
+		res, err := tx.Exec(`INSERT INTO companies (category, country, key, number, name, address, link1, link2, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			c.Category, c.Country, key, c.Number, c.Name, c.Address, c.Link1, c.Link2, now, now)
+		if err != nil {
+			return 0, err
+		}
+		return res.LastInsertId()
+	case err != nil:
+		return 0, err
+	}
+	_, err = tx.Exec(`UPDATE companies SET
+		number = CASE WHEN ? > 0 THEN ? ELSE number END,
+		name = COALESCE(NULLIF(?, ''), name),
+		address = COALESCE(NULLIF(?, ''), address),
+		link1 = COALESCE(NULLIF(?, ''), link1),
+		link2 = COALESCE(NULLIF(?, ''), link2),
+		updated_at = ?
+		WHERE id = ?`,
+		c.Number, c.Number, c.Name, c.Address, c.Link1, c.Link2, now, id)
+	return id, err
+}
+
+func addEmail(tx *sql.Tx, id int64, email, source, now string) error {
+	_, err := tx.Exec(`INSERT INTO emails (company_id, email, source, first_seen, last_seen) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (company_id, email, source) DO UPDATE SET last_seen = excluded.last_seen`,
+		id, strings.TrimSpace(email), source, now, now)
+	return err
+}
+
+// StoreGroup 数据库中一个目录、国家的公司数
+type StoreGroup struct {
+	Category string
+	Country  string
+	Count    int
+}
+
+// Groups 数据库中的目录和国家，按 InputFilter 的类型、国家条件过滤（阶段、日期条件不适用）
+func (s *Store) Groups(filter InputFilter) ([]StoreGroup, error) {
+	filter.Stage = ""
+	rows, err := s.db.Query(`SELECT category, country, COUNT(*) FROM companies GROUP BY category, country ORDER BY category, country`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var groups []StoreGroup
+	for rows.Next() {
+		var g StoreGroup
+		if err := rows.Scan(&g.Category, &g.Country, &g.Count); err != nil {
+			return nil, err
+		}
+		if filter.match(InputFile{Type: g.Category, Country: g.Country}) {
+			groups = append(groups, g)
+		}
+	}
+	return groups, rows.Err()
+}
+
+// 邮箱来源的优先级：官网 > 详情页 > 导入，同一来源取最近看到的
+var emailRank = map[string]int{EmailFromWebsite: 3, EmailFromProfile: 2, EmailImported: 1}
+
+// Companies 读出一个目录、国家的全部公司，按 Number 排序。
+// Email 为抓取到的最佳邮箱，GuessedEmail 为最近的猜测，Candidates 为全部候选邮箱，
+// Outcome 等取自最近一次官网抓取。
+func (s *Store) Companies(category, country string) ([]Company, error) {
+	rows, err := s.db.Query(`SELECT id, number, name, address, link1, link2 FROM companies WHERE category = ? AND country = ?`, category, country)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int64]*Company)
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		c := &Company{Category: category, Country: country}
+		if err := rows.Scan(&id, &c.Number, &c.Name, &c.Address, &c.Link1, &c.Link2); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		byID[id] = c
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// 最近一次官网抓取
+	fetches, err := s.db.Query(`SELECT f.company_id, f.final_url, f.pass, f.outcome, f.tls FROM fetches f
+		JOIN companies c ON c.id = f.company_id
+		WHERE c.category = ? AND c.country = ? ORDER BY f.id`, category, country)
+	if err != nil {
+		return nil, err
+	}
+	for fetches.Next() {
+		var id int64
+		var finalURL, pass, outcome, tls string
+		if err := fetches.Scan(&id, &finalURL, &pass, &outcome, &tls); err != nil {
+			fetches.Close()
+			return nil, err
+		}
+		if c := byID[id]; c != nil {
+			c.FinalURL, c.Pass, c.Outcome, c.TLS = finalURL, pass, outcome, tls
+		}
+	}
+	fetches.Close()
+	if err := fetches.Err(); err != nil {
+		return nil, err
+	}
+
+	emails, err := s.db.Query(`SELECT e.company_id, e.email, e.source, e.last_seen FROM emails e
+		JOIN companies c ON c.id = e.company_id
+		WHERE c.category = ? AND c.country = ? ORDER BY e.last_seen, e.first_seen, e.rowid`, category, country)
+	if err != nil {
+		return nil, err
+	}
+	guessSeen := make(map[int64]string)
+	for emails.Next() {
+		var id int64
+		var email, source, seen string
+		if err := emails.Scan(&id, &email, &source, &seen); err != nil {
+			emails.Close()
+			return nil, err
+		}
+		c := byID[id]
+		if c == nil {
+			continue
+		}
+		c.AddCandidates(source, email)
+		switch {
+		case source == EmailGuessed:
+			// 同一次猜测的多个候选中取第一个（最常用的前缀）
+			if seen > guessSeen[id] {
+				c.GuessedEmail, guessSeen[id] = email, seen
+			}
+		case emailRank[source] >= emailRank[c.EmailSource] || c.Email == "":
+			c.Email, c.EmailSource = email, source
+		}
+	}
+	emails.Close()
+	if err := emails.Err(); err != nil {
+		return nil, err
+	}
+
+	companies := make([]Company, 0, len(ids))
+	for _, id := range ids {
+		c := byID[id]
+		if c.Email != "" {
+			c.GuessedEmail = ""
+		}
+		if c.EmailSource == EmailImported {
+			c.EmailSource = ""
+		}
+		companies = append(companies, *c)
+	}
+	sort.SliceStable(companies, func(i, j int) bool { return companies[i].Number < companies[j].Number })
+	return companies, nil
+}
+
+// StoreSink 把流水线结果写入数据库；Close 不关闭数据库
+type StoreSink struct {
+	Store *Store
+}
+
+func (s StoreSink) Write(c Company) error { return s.Store.Save(c) }
+
+func (s StoreSink) Close() error { return nil }
@@ -0,0 +1,138 @@
+package crawler
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// TLS错误类别，写入每行结果，便于区分“证书有问题”和“站点已失效”
+const (
+	TLSUnknownAuthority = "unknown_authority" // 自签名或证书链不完整
+	TLSHostnameMismatch = "hostname_mismatch" // 证书域名不匹配
+	TLSExpired          = "expired"           // 证书过期或尚未生效
+	TLSInvalid          = "invalid"           // 其他证书校验失败
+	TLSNotTLS           = "not_tls"           // 对端不是TLS服务
+	TLSHandshake        = "handshake"         // 握手失败（协议、加密套件等）
+)
+
+// TLSPolicy 决定某个host是否跳过证书校验。
+// 默认全部校验，只有白名单中的host（及其子域名）直接信任。
+type TLSPolicy struct {
+	allow []string
+}
+
+// NewTLSPolicy 用给定的白名单host创建策略
+func NewTLSPolicy(hosts ...string) *TLSPolicy {
+	p := &TLSPolicy{}
+	p.Allow(hosts...)
+	return p
+}
+
+// LoadTLSPolicy 从文件读取白名单，每行一个host，#开头为注释。
+// 文件不存在时返回空策略。
+func LoadTLSPolicy(path string) (*TLSPolicy, error) {
+	p := NewTLSPolicy()
+	if path == "" {
+		return p, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return p, nil
+		}
+		return nil, fmt.Errorf("读取TLS白名单失败: %v", err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p.Allow(line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取TLS白名单失败: %v", err)
+	}
+	return p, nil
+}
+
+// Allow 把host加入白名单
+func (p *TLSPolicy) Allow(hosts ...string) {
+	for _, h := range hosts {
+		h = strings.ToLower(strings.TrimSpace(h))
+		h = strings.TrimPrefix(h, "*.")
+		if h != "" {
+			p.allow = append(p.allow, h)
+		}
+	}
+}
+
+// Trusted 判断host是否在白名单中，子域名同样视为信任
+func (p *TLSPolicy) Trusted(host string) bool {
+	if p == nil {
+		return false
+	}
+	host = strings.ToLower(host)
+	for _, h := range p.allow {
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
+
+// Config 返回访问host时使用的TLS配置，只有白名单host跳过校验
+func (p *TLSPolicy) Config(host string) *tls.Config {
+	return tlsConfig(p.Trusted(host))
+}
+
+func tlsConfig(insecure bool) *tls.Config {
+	return &tls.Config{InsecureSkipVerify: insecure}
+}
+
+// ClassifyTLSError 返回错误对应的TLS类别，不是TLS错误时返回空字符串
+func ClassifyTLSError(err error) string {
+	if err == nil {
+		return ""
+	}
+	var unknownAuth x509.UnknownAuthorityError
+	var hostErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	var recordErr tls.RecordHeaderError
+	var verifyErr *tls.CertificateVerificationError
+	switch {
+	case errors.As(err, &unknownAuth):
+		return TLSUnknownAuthority
+	case errors.As(err, &hostErr):
+		return TLSHostnameMismatch
+	case errors.As(err, &invalidErr):
+		if invalidErr.Reason == x509.Expired {
+			return TLSExpired
+		}
+		return TLSInvalid
+	case errors.As(err, &verifyErr):
+		return TLSInvalid
+	case errors.As(err, &recordErr):
+		return TLSNotTLS
+	}
+	msg := err.Error()
+	if strings.Contains(msg, "tls: ") || strings.Contains(msg, "x509: ") {
+		return TLSHandshake
+	}
+	return ""
+}
+
+// isCertError 判断是否属于可以通过跳过校验来绕过的证书问题
+func isCertError(class string) bool {
+	switch class {
+	case TLSUnknownAuthority, TLSHostnameMismatch, TLSExpired, TLSInvalid:
+		return true
+	}
+	return false
+}
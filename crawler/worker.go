@@ -0,0 +1,189 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// 连续多少次连不上协调器后退出
+const maxCoordinatorFailures = 10
+
+// Worker 分布式模式的worker：向协调器领取一批任务，用 Pipeline 的抓取器处理，
+// 处理期间定期续租，完成后交回结果。协调器返回410时退出。
+type Worker struct {
+	Coordinator string // 协调器地址，如 http://10.0.0.2:8080
+	Token       string // 与协调器的 -token 相同
+	ID          string
+	Pipeline    *Pipeline // 使用其中的 ENF、Website、Retry 抓取器
+	Workers     int       // 同时处理的任务数
+	Batch       int       // 每次领取的任务数
+	Heartbeat   time.Duration
+	Poll        time.Duration // 暂时没有任务时的等待间隔
+	Client      *http.Client
+
+	// Stop 关闭后不再领取新任务，手上的任务处理完再退出
+	Stop <-chan struct{}
+}
+
+// NewWorker 创建worker，默认每30秒续租一次
+func NewWorker(coordinator, id string, p *Pipeline) *Worker {
+	return &Worker{
+		Coordinator: strings.TrimRight(coordinator, "/"),
+		ID:          id,
+		Pipeline:    p,
+		Workers:     10,
+		Batch:       10,
+		Heartbeat:   30 * time.Second,
+		Poll:        2 * time.Second,
+		Client:      &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Run 循环领取和处理任务，直到全部完成、Stop 关闭或ctx取消
+func (w *Worker) Run(ctx context.Context) error {
+	failures := 0
+	for {
+		select {
+		case <-w.Stop:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+		var lease Lease
+		status, err := w.post(ctx, PathLease, LeaseRequest{Worker: w.ID, Max: w.Batch}, &lease)
+		if err != nil {
+			if failures++; failures >= maxCoordinatorFailures {
+				return fmt.Errorf("连接协调器失败: %v", err)
+			}
+			log.Printf("领取任务失败：%v\n", err)
+			w.sleep(ctx)
+			continue
+		}
+		failures = 0
+		switch status {
+		case http.StatusOK:
+		case http.StatusGone:
+			log.Println("协调器上的任务已全部完成")
+			return nil
+		case http.StatusNoContent:
+			w.sleep(ctx)
+			continue
+		case http.StatusUnauthorized:
+			return fmt.Errorf("协调器拒绝了令牌，请检查 -token")
+		default:
+			return fmt.Errorf("领取任务失败，状态码: %d", status)
+		}
+
+		results, ok := w.process(ctx, &lease)
+		if !ok {
+			continue
+		}
+		// 退出时ctx已取消，提交结果单独给一段时间
+		submitCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		status, err = w.post(submitCtx, PathComplete, LeaseResult{ID: lease.ID, Results: results}, nil)
+		cancel()
+		switch {
+		case err != nil:
+			log.Printf("提交租约 %s 的结果失败：%v\n", lease.ID, err)
+		case status == http.StatusConflict:
+			log.Printf("租约 %s 已过期，结果被丢弃\n", lease.ID)
+		case status == http.StatusGone:
+			log.Printf("协调器已关闭，租约 %s 的结果被丢弃\n", lease.ID)
+			return nil
+		case status != http.StatusNoContent:
+			log.Printf("提交租约 %s 的结果失败，状态码: %d\n", lease.ID, status)
+		}
+	}
+}
+
+// process 处理租约中的任务，期间定期续租。租约丢失时返回 false。
+// ctx 取消时不交回任何结果，任务由协调器重新分配。
+func (w *Worker) process(ctx context.Context, lease *Lease) ([]Company, bool) {
+	leaseCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	lost := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(w.Heartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-leaseCtx.Done():
+				return
+			case <-ticker.C:
+			}
+			status, err := w.post(leaseCtx, PathHeartbeat, Lease{ID: lease.ID}, nil)
+			if err == nil && status == http.StatusNotFound {
+				log.Printf("租约 %s 已过期，放弃处理\n", lease.ID)
+				close(lost)
+				cancel()
+				return
+			}
+			if err != nil && leaseCtx.Err() == nil {
+				log.Printf("续租 %s 失败：%v\n", lease.ID, err)
+			}
+		}
+	}()
+
+	in := make(chan Company)
+	go func() {
+		defer close(in)
+		for _, c := range lease.Jobs {
+			in <- c
+		}
+	}()
+	var results []Company
+	for c := range runStage(in, w.Workers, func(c Company) Company { return w.Pipeline.Enrich(leaseCtx, c) }) {
+		results = append(results, c)
+	}
+	select {
+	case <-lost:
+		return nil, false
+	default:
+	}
+	if ctx.Err() != nil {
+		return nil, true
+	}
+	return results, true
+}
+
+func (w *Worker) sleep(ctx context.Context) {
+	select {
+	case <-time.After(w.Poll):
+	case <-w.Stop:
+	case <-ctx.Done():
+	}
+}
+
+// post 以JSON发送请求，out 不为nil且状态码为200时解析响应
+func (w *Worker) post(ctx context.Context, path string, body, out any) (int, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.Coordinator+path, bytes.NewReader(data))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+w.Token)
+	}
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if out != nil && resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return resp.StatusCode, fmt.Errorf("解析协调器响应失败: %v", err)
+		}
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,164 @@
+package crawler
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// FieldCustomerType 导出表中的客户类型列：Installers / Sellers / Panel Manufacturers ...
+const FieldCustomerType = "Customer Type"
+
+// ExportColumns XLSX导出的列：原 procedure3.py 生成的表，Email 后加上按域名猜测、未经验证的 Guessed Email
+var ExportColumns = []string{FieldNumber, FieldCountry, FieldName, FieldEmail, FieldGuessed, FieldCustomerType, FieldLink2}
+
+const (
+	minColumnWidth = 8
+	maxColumnWidth = 60
+	maxSheetName   = 31 // Excel 工作表名的长度上限
+)
+
+// CustomerType 目录类型对应的客户类型，见 Category.CustomerType，如 installer → Installers
+func CustomerType(category string) string {
+	if c, err := LookupCategory(category); err == nil {
+		return c.CustomerType
+	}
+	if category == "" {
+		return "Unknown"
+	}
+	return strings.ToUpper(category[:1]) + category[1:] + "s"
+}
+
+func exportRow(c Company) []interface{} {
+	return []interface{}{c.Number, c.Country, c.Name, c.Email, c.GuessedEmail, CustomerType(c.Category), c.Link2}
+}
+
+// Workbook XLSX导出：每个工作表冻结表头、开启筛选，列宽按内容设置
+type Workbook struct {
+	f      *excelize.File
+	header int // 表头样式
+	sheets int
+	names  map[string]bool
+}
+
+// NewWorkbook 创建空工作簿
+func NewWorkbook() (*Workbook, error) {
+	f := excelize.NewFile()
+	header, err := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true},
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"#D9E1F2"}},
+	})
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &Workbook{f: f, header: header, names: make(map[string]bool)}, nil
+}
+
+// AddSheet 添加一个工作表写入 rows，name 中Excel不允许的字符替换为空格，过长时截断，重名时加序号
+func (w *Workbook) AddSheet(name string, rows []Company) error {
+	name = w.sheetName(name)
+	if w.sheets == 0 {
+		// 新工作簿自带的 Sheet1 改名后使用
+		if err := w.f.SetSheetName(w.f.GetSheetName(0), name); err != nil {
+			return err
+		}
+	} else if _, err := w.f.NewSheet(name); err != nil {
+		return err
+	}
+	w.sheets++
+
+	widths := make([]int, len(ExportColumns))
+	header := make([]interface{}, len(ExportColumns))
+	for i, col := range ExportColumns {
+		header[i] = col
+		widths[i] = utf8.RuneCountInString(col)
+	}
+	if err := w.f.SetSheetRow(name, "A1", &header); err != nil {
+		return err
+	}
+	for i, c := range rows {
+		row := exportRow(c)
+		for j, v := range row {
+			if n := utf8.RuneCountInString(fmt.Sprint(v)); n > widths[j] {
+				widths[j] = n
+			}
+		}
+		cell, _ := excelize.CoordinatesToCellName(1, i+2)
+		if err := w.f.SetSheetRow(name, cell, &row); err != nil {
+			return err
+		}
+	}
+
+	last, _ := excelize.ColumnNumberToName(len(ExportColumns))
+	if err := w.f.SetCellStyle(name, "A1", last+"1", w.header); err != nil {
+		return err
+	}
+	for i, width := range widths {
+		col, _ := excelize.ColumnNumberToName(i + 1)
+		width = min(max(width+2, minColumnWidth), maxColumnWidth)
+		if err := w.f.SetColWidth(name, col, col, float64(width)); err != nil {
+			return err
+		}
+	}
+	if err := w.f.SetPanes(name, &excelize.Panes{
+		Freeze:      true,
+		YSplit:      1,
+		TopLeftCell: "A2",
+		ActivePane:  "bottomLeft",
+	}); err != nil {
+		return err
+	}
+	return w.f.AutoFilter(name, fmt.Sprintf("A1:%s%d", last, len(rows)+1), nil)
+}
+
+func (w *Workbook) sheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return ' '
+		}
+		return r
+	}, name)
+	name = strings.Trim(strings.TrimSpace(name), "'")
+	if name == "" {
+		name = "Sheet"
+	}
+	base := truncateRunes(name, maxSheetName)
+	name = base
+	for i := 2; w.names[strings.ToLower(name)]; i++ {
+		suffix := fmt.Sprintf(" (%d)", i)
+		name = truncateRunes(base, maxSheetName-len(suffix)) + suffix
+	}
+	w.names[strings.ToLower(name)] = true
+	return name
+}
+
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+// Save 原子地写入 path
+func (w *Workbook) Save(path string) error {
+	if w.sheets == 0 {
+		return fmt.Errorf("工作簿 %s 没有数据", path)
+	}
+	out, err := CreateAtomic(path)
+	if err != nil {
+		return fmt.Errorf("无法创建 %s: %v", path, err)
+	}
+	defer out.Close()
+	if err := w.f.Write(out.File); err != nil {
+		return fmt.Errorf("写入 %s 失败: %v", path, err)
+	}
+	return out.Commit()
+}
+
+// Close 释放工作簿占用的资源
+func (w *Workbook) Close() error {
+	return w.f.Close()
+}
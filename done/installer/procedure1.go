@@ -1,41 +1,21 @@
 package main
 
 import (
-	"compress/gzip"
-	"context"
-	"crypto/tls"
+	"bytes"
 	"encoding/csv"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log"
-	"net"
-	"net/http"
-	"net/url"
 	"os"
-	"regexp"
-	"sort"
 	"strconv"
-	"strings"
 	"sync"
 	"time"
 	"github.com/PuerkitoBio/goquery"
-	"github.com/andybalholm/brotli"
-	"golang.org/x/net/proxy"
-	"errors"
+	"go-crawler/crawler"
 )
 
-// 代理账号信息
-var proxyAccounts = []struct {
-	Username string
-	Password string
-}{
-	{"yangyangmao-rotate", "yangyangmao"},
-	{"iu7zso75luk-rotate", "iu7zso75luk"},
-	{"shengshi-rotate", "shengshi"},
-	{"xixiwenxuanhe-rotate", "xixiwenxuanhe"},
-}
-
 type Company struct {
 	Number  string
 	Name    string
@@ -43,454 +23,364 @@ type Company struct {
 	Link1   string
 	Link2   string
 	Email   string
+	TLS     string // 证书校验失败的类别
+	Outcome string // 结果代码，见 crawler.Outcome*
+	Pass    string // 结果由第几轮产生，见 crawler.PassMain / PassRetry
+	Seq     int    // 在输入文件中的顺序，输出按它排序
 }
 
-var httpClient = &http.Client{
-	Timeout: 5 * time.Second, // 本地网络最多5秒
-	Transport: &http.Transport{
-		DialContext: (&net.Dialer{
-			Timeout:   2 * time.Second,  // 连接超时
-			KeepAlive: 30 * time.Second, // 保持连接
-		}).DialContext,
-		TLSHandshakeTimeout:   2 * time.Second,
-		ResponseHeaderTimeout: 3 * time.Second,
-		ExpectContinueTimeout: 1 * time.Second,
-		MaxIdleConns:          10,
-		IdleConnTimeout:       30 * time.Second,
-		TLSClientConfig: &tls.Config{
-			InsecureSkipVerify: true,
-		},
-	},
+// job 一行待处理的记录；所有文件共用一个调度器，按 file 轮流分配worker
+type job struct {
+	file    string
+	company Company
+	handle  func(Company)
 }
 
-// 超时读取器，用于处理读取超时
-type timeoutReader struct {
-	r       io.Reader
-	timeout time.Duration
+func newScheduler(maxConcurrency, perDomain int) *crawler.Scheduler[job] {
+	return crawler.NewGroupScheduler(maxConcurrency, perDomain,
+		func(j job) string { return j.file },
+		func(j job) string { return crawler.RegisteredDomain(j.company.Link2) },
+		func(j job) { j.handle(j.company) })
 }
 
-func (tr *timeoutReader) Read(p []byte) (n int, err error) {
-	ch := make(chan readResult, 1)
-	
-	go func() {
-		n, err := tr.r.Read(p)
-		ch <- readResult{n, err}
-	}()
-	
-	select {
-	case res := <-ch:
-		return res.n, res.err
-	case <-time.After(tr.timeout):
-		return 0, errors.New("读取超时")
-	}
-}
-
-type readResult struct {
-	n   int
-	err error
-}
+func processFile(input crawler.InputFile, run *crawler.Run, store *crawler.Store, pool *crawler.Scheduler[job], fetcher, retryFetcher *crawler.Fetcher, shutdown *crawler.Shutdown) {
+	start := time.Now()
+	inputFile := input.Path
 
-// 创建代理拨号器
-func createProxyDialer(proxyURL string) (proxy.Dialer, error) {
-	// 解析代理URL
-	parsedURL, err := url.Parse(proxyURL)
+	file, err := os.Open(inputFile)
 	if err != nil {
-		return nil, fmt.Errorf("解析代理URL失败: %v", err)
+		log.Printf("无法打开CSV文件 %s：%v\n", inputFile, err)
+		return
 	}
-	
-	// 创建SOCKS5代理拨号器
-	auth := &proxy.Auth{}
-	if parsedURL.User != nil {
-		auth.User = parsedURL.User.Username()
-		if password, ok := parsedURL.User.Password(); ok {
-			auth.Password = password
-		}
+	defer file.Close()
+	// 按表头取列，Address、Link1 可以没有
+	reader, err := crawler.NewCSVReader(file, crawler.FieldNumber, crawler.FieldName, crawler.FieldLink2)
+	if err != nil {
+		log.Printf("读取CSV失败 %s：%v\n", inputFile, err)
+		return
 	}
-	
-	// 连接代理服务器
-	dialer, err := proxy.SOCKS5("tcp", parsedURL.Host, auth, proxy.Direct)
+	if report := reader.Mapping.Report(); report != "" {
+		log.Printf("%s：%s\n", inputFile, report)
+	}
+
+	// 结果按输入顺序（即Number顺序）写入同目录下的临时文件，运行中也能查看，结束时改名；
+	// 留到第二轮重试的行追加在最后
+	outputFile := run.OutputPath(input, "Procedure1")
+	outfile, err := crawler.CreateAtomic(outputFile)
 	if err != nil {
-		return nil, fmt.Errorf("创建SOCKS5代理拨号器失败: %v", err)
+		log.Printf("无法创建输出CSV：%v\n", err)
+		return
 	}
-	
-	return dialer, nil
-}
+	defer outfile.Close()
+	writer := csv.NewWriter(outfile)
+	writer.Write([]string{"Number", "Company Name", "Company Website", "Email", "TLS", "Outcome", "Pass"})
+	writer.Flush()
 
-// 使用代理请求网页
-func requestWithProxy(targetURL string) (*http.Response, bool, error) {
-	// 尝试所有代理
-	for _, account := range proxyAccounts {
-		proxyURL := fmt.Sprintf("socks5://%s:%s@p.webshare.io:80", account.Username, account.Password)
-		
-		// 创建一个自定义的拨号器
-		dialer, err := createProxyDialer(proxyURL)
-		if err != nil {
-			continue
+	// 统计输出，结束时记入 manifest
+	stats := crawler.FileManifest{Input: inputFile}
+	// 前面的行都完成后按顺序写出（Reorder 内部加锁）
+	order := crawler.NewReorder(0, func(company Company) error {
+		writer.Write([]string{company.Number, company.Name, company.Link2, company.Email, company.TLS, company.Outcome, company.Pass})
+		writer.Flush() // 确保立即写入
+		stats.Count(company.Outcome, company.Pass)
+		if store != nil {
+			if err := store.Save(record(input, company, company.Email)); err != nil {
+				log.Println(err)
+			}
 		}
-		
-		transport := &http.Transport{
-			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
-				// 设置连接超时为1秒
-				ctx, cancel := context.WithTimeout(ctx, 1*time.Second)
-				defer cancel()
-				return dialer.Dial(network, addr)
-			},
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: true,
-			},
-			// 设置TLS握手超时
-			TLSHandshakeTimeout: 2 * time.Second,
-			// 设置响应头超时
-			ResponseHeaderTimeout: 2 * time.Second,
+		return writer.Error()
+	})
+
+	var mu sync.Mutex      // 保护 deferred
+	var deferred []Company // 第一轮请求失败、留到最后重试的记录
+	// 写入一行结果，出错时在最后 Flush 返回
+	write := func(company Company) {
+		order.Put(company.Seq, company)
+	}
+	handle := func(company Company) {
+		link := company.Link2
+		save := func(outcome string) {
+			company.Outcome = outcome
+			write(company)
 		}
-		
-		client := &http.Client{
-			Transport: transport,
-			Timeout:   3 * time.Second, // 将30秒改为3秒（代理网络）
+		if link == "" {
+			save(crawler.OutcomeNoLink)
+			fmt.Printf("%s,%s,%s,%s,,E1001,\n", company.Number, company.Name, company.Address, link)
+			return
 		}
-		
-		req, err := http.NewRequest("GET", targetURL, nil)
-		if err != nil {
-			continue
+
+		var resp *crawler.Response
+		var err error
+		if company.Pass == crawler.PassRetry {
+			// 第二轮：更长超时、其他代理，并尝试 http/https、www 变体
+			resp, err = crawler.FetchAlternates(shutdown.Context(), retryFetcher, link)
+		} else {
+			resp, err = fetcher.Fetch(shutdown.Context(), link)
+			if retryFetcher != nil && crawler.Retryable(err) {
+				company.TLS, company.Outcome = crawler.TLSErrorOf(err), crawler.OutcomeOf(err)
+				mu.Lock()
+				deferred = append(deferred, company)
+				mu.Unlock()
+				// 让出位置，后面的行照常写出，重试结果追加在后面
+				order.Skip(company.Seq)
+				fmt.Printf("%s,%s,%s,%s,,%s,请求失败，留到第二轮重试: %v\n", company.Number, company.Name, company.Address, link, company.Outcome, err)
+				return
+			}
 		}
-		
-		// 添加完整的请求头
-		req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36")
-		req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
-		req.Header.Set("Accept-Language", "de-DE,de;q=0.9,en;q=0.8") // 使用德语首选
-		req.Header.Set("Accept-Encoding", "gzip, deflate, br")
-		req.Header.Set("Connection", "keep-alive")
-		req.Header.Set("Referer", "https://www.google.com/")
-		req.Header.Set("Upgrade-Insecure-Requests", "1")
-		req.Header.Set("Cache-Control", "max-age=0")
-		
-		resp, err := client.Do(req)
+		company.TLS = crawler.TLSErrorOf(err)
 		if err != nil {
-			continue
+			outcome := crawler.OutcomeOf(err)
+			save(outcome)
+			if crawler.IsSkipped(outcome) {
+				fmt.Printf("%s,%s,%s,%s,,%s,跳过: %v\n", company.Number, company.Name, company.Address, link, outcome, err)
+			} else {
+				fmt.Printf("%s,%s,%s,%s,,%s,请求失败: %v\n", company.Number, company.Name, company.Address, link, outcome, err)
+			}
+			return
 		}
-		
-		// 如果请求成功
-		if resp.StatusCode == 200 {
-			return resp, true, nil
+		doc, err := goquery.NewDocumentFromReader(bytes.NewReader(resp.Body))
+		if err != nil {
+			save(crawler.OutcomeParseFailed)
+			fmt.Printf("%s,%s,%s,%s,,%s,解析失败: %v\n", company.Number, company.Name, company.Address, link, crawler.OutcomeParseFailed, err)
+			return
 		}
-		
-		resp.Body.Close()
-	}
-	
-	return nil, false, fmt.Errorf("所有代理均请求失败")
-}
 
-func extractEmail(text string) string {
-	// 支持 @、[at]、(at) 三种写法，允许中间有空格
-	patterns := []string{
-		`[\w\.-]+\s*@\s*[\w\.-]+\.[a-zA-Z]{2,}`,
-		`[\w\.-]+\s*\[at\]\s*[\w\.-]+\.[a-zA-Z]{2,}`,
-		`[\w\.-]+\s*\(at\)\s*[\w\.-]+\.[a-zA-Z]{2,}`,
-	}
-	for _, pat := range patterns {
-		re := regexp.MustCompile("(?i)" + pat)
-		match := re.FindString(text)
-		if match != "" {
-			// 标准化邮箱
-			match = strings.ReplaceAll(match, "(at)", "@")
-			match = strings.ReplaceAll(match, "[at]", "@")
-			match = strings.ReplaceAll(match, " ", "")
-			return match
+		email := crawler.ExtractEmail(doc.Text())
+		company.Email = email
+
+		note := ""
+		if resp.UsedProxy {
+			note += ",切换代理访问成功"
+		}
+		if resp.TLSError != "" {
+			note += ",证书异常(" + resp.TLSError + ")已跳过校验"
+		}
+		if resp.FromCache {
+			note += ",来自缓存"
+		}
+		if email != "" {
+			save(crawler.OutcomeOK)
+			fmt.Printf("%s,%s,%s,%s,%s,成功%s\n", company.Number, company.Name, company.Address, link, email, note)
+		} else if resp.StatusCode == 200 {
+			save(crawler.OutcomeNoEmail)
+			fmt.Printf("%s,%s,%s,%s,,网站源代码并没有邮件信息，需要进一步处理...%s\n", company.Number, company.Name, company.Address, link, note)
+		} else {
+			save(crawler.OutcomeBadStatus)
+			fmt.Printf("%s,%s,%s,%s,,状态码: %d\n", company.Number, company.Name, company.Address, link, resp.StatusCode)
 		}
 	}
-	return ""
-}
-
-func processFile(inputFile string, maxConcurrency int) {
-	start := time.Now()
 
-	file, err := os.Open(inputFile)
-	if err != nil {
-		log.Printf("无法打开CSV文件 %s：%v\n", inputFile, err)
-		return
-	}
-	defer file.Close()
-	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
-	if err != nil {
-		log.Printf("读取CSV失败 %s：%v\n", inputFile, err)
-		return
+	// 边读边提交到共享调度器，本文件队列满时读取会等待；
+	// 每行完成后只要前面的行都已完成就立即按顺序写出
+	var wg sync.WaitGroup
+	done := func(company Company) {
+		defer wg.Done()
+		handle(company)
 	}
-
-	var companies []Company
-	for i, row := range records {
-		if i == 0 {
-			continue // 跳过表头
+	total := 0
+	for !shutdown.Interrupted() {
+		c, _, err := reader.Read()
+		if err == io.EOF {
+			break
 		}
-		if len(row) < 5 {
+		var rowErr *crawler.RowError
+		if errors.As(err, &rowErr) {
+			log.Printf("%s：跳过%v\n", inputFile, err)
 			continue
 		}
-		companies = append(companies, Company{
-			Number:  row[0],
-			Name:    row[1],
-			Address: row[2],
-			Link1:   row[3],
-			Link2:   row[4],
-			Email:   "",
-		})
+		if err != nil {
+			log.Printf("读取CSV失败 %s：%v\n", inputFile, err)
+			break
+		}
+		wg.Add(1)
+		pool.Submit(job{file: inputFile, handle: done, company: Company{
+			Number:  strconv.Itoa(c.Number),
+			Name:    c.Name,
+			Address: c.Address,
+			Link1:   c.Link1,
+			Link2:   c.Link2,
+			Pass:    crawler.PassMain,
+			Seq:     total,
+		}})
+		total++
 	}
-	
-	fmt.Printf("读取到 %d 条记录\n", len(companies))
+	wg.Wait()
 
-	// 创建临时文件用于存储处理结果
-	baseName := inputFile
-	if idx := strings.LastIndex(inputFile, "/"); idx != -1 {
-		baseName = inputFile[idx+1:]
+	// 第二轮：第一轮请求失败（超时、代理失败）的记录再试一次；中断时不再重试，按第一轮结果写出
+	if len(deferred) > 0 && !shutdown.Interrupted() {
+		fmt.Printf("%s：第二轮重试 %d 条请求失败的记录\n", inputFile, len(deferred))
+		for _, company := range deferred {
+			company.Pass = crawler.PassRetry
+			wg.Add(1)
+			pool.Submit(job{file: inputFile, handle: done, company: company})
+		}
+		wg.Wait()
+	} else {
+		for _, company := range deferred {
+			write(company)
+		}
 	}
-	tempFile := "procedure1/temp_" + strings.Replace(baseName, "Company", "Procedure1", 1)
-	outputFile := "procedure1/" + strings.Replace(baseName, "Company", "Procedure1", 1)
-	
-	// 确保目录存在
-	os.MkdirAll("procedure1", 0755)
-	
-	tempOut, err := os.Create(tempFile)
-	if err != nil {
-		log.Printf("无法创建临时CSV：%v\n", err)
+	fmt.Printf("%s：读取到 %d 条记录\n", inputFile, total)
+	// 中断时未完成的行留下空缺，其余照常按顺序写出
+	if err := order.Flush(); err != nil {
+		log.Printf("写入输出CSV失败：%v\n", err)
+	}
+	if shutdown.Interrupted() {
+		// 文件名标记为未完成
+		outputFile = crawler.IncompletePath(outputFile)
+	}
+	if err := outfile.CommitAs(outputFile); err != nil {
+		log.Println(err)
 		return
 	}
-	defer tempOut.Close()
-	tempWriter := csv.NewWriter(tempOut)
-	defer tempWriter.Flush()
-	
-	// 写入表头
-	tempWriter.Write([]string{"Number", "Company Name", "Company Website", "Email"})
+	if shutdown.Interrupted() {
+		fmt.Printf("%s：处理被中断，已完成的 %d 条记录写入 %s\n", inputFile, stats.Rows, outputFile)
+	}
 
-	var wg sync.WaitGroup
-	var mu sync.Mutex // 保护文件写入
-	sem := make(chan struct{}, maxConcurrency) // 控制最大并发
-	
-	for i := range companies {
-		wg.Add(1)
-		sem <- struct{}{} // 占用一个名额
-		go func(i int) {
-			defer wg.Done()
-			defer func() { <-sem }() // 释放名额
+	fmt.Printf("%s：邮箱提取完成，结果已保存到 %s\n", inputFile, outputFile)
+	fmt.Printf("%s：总耗时：%v\n", inputFile, time.Since(start))
 
-			link := companies[i].Link2
-			company := companies[i] // 拷贝，避免并发问题
-			if link == "" {
-				company.Email = ""
-				mu.Lock()
-				tempWriter.Write([]string{company.Number, company.Name, company.Link2, ""})
-				tempWriter.Flush() // 确保立即写入
-				mu.Unlock()
-				fmt.Printf("%s,%s,%s,%s,,E1001,\n", company.Number, company.Name, company.Address, link)
-				return
-			}
+	failRate := 0.0
+	if stats.Rows > 0 {
+		failRate = float64(stats.Failed) / float64(stats.Rows) * 100
+	}
+	fmt.Printf("%s：总记录数：%d，失败数：%d，失败率：%.2f%%，跳过数：%d，第二轮成功数：%d\n", inputFile, stats.Rows, stats.Failed, failRate, stats.Skipped, stats.RetryOK)
+	stats.Output = outputFile
+	if err := run.AddFile(stats); err != nil {
+		log.Println(err)
+	}
+}
 
-			// 标准请求
-			req, err := http.NewRequest("GET", link, nil)
-			if err != nil {
-				company.Email = ""
-				mu.Lock()
-				tempWriter.Write([]string{company.Number, company.Name, company.Link2, ""})
-				tempWriter.Flush()
-				mu.Unlock()
-				fmt.Printf("%s,%s,%s,%s,,请求失败: %v\n", company.Number, company.Name, company.Address, link, err)
-				return
-			}
-			
-			req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36")
-			req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
-			req.Header.Set("Accept-Language", "zh-CN,zh;q=0.9,en;q=0.8")
-			req.Header.Set("Accept-Encoding", "gzip, deflate, br")
-			req.Header.Set("Connection", "keep-alive")
-			req.Header.Set("Referer", "https://www.google.com/")
-			req.Header.Set("Upgrade-Insecure-Requests", "1")
-			req.Header.Set("Cache-Control", "max-age=0")
-			req.Header.Set("Pragma", "no-cache")
-			
-			resp, err := httpClient.Do(req)
-			usedProxy := false
-			
-			// 如果普通请求失败，尝试使用代理
-			if err != nil || resp == nil || resp.StatusCode != 200 {
-				if resp != nil {
-					resp.Body.Close()
-				}
-				// 尝试使用代理
-				proxyResp, proxySuccess, _ := requestWithProxy(link)
-				if proxySuccess && proxyResp != nil {
-					resp = proxyResp
-					usedProxy = true
-				} else {
-					company.Email = ""
-					mu.Lock()
-					tempWriter.Write([]string{company.Number, company.Name, company.Link2, ""})
-					tempWriter.Flush()
-					mu.Unlock()
-					fmt.Printf("%s,%s,%s,%s,,请求失败(代理也失败)\n", company.Number, company.Name, company.Address, link)
-					return
-				}
-			}
-			if resp == nil {
-				company.Email = ""
-				mu.Lock()
-				tempWriter.Write([]string{company.Number, company.Name, company.Link2, ""})
-				tempWriter.Flush()
-				mu.Unlock()
-				fmt.Printf("%s,%s,%s,%s,,无法获取响应\n", company.Number, company.Name, company.Address, link)
-				return
-			}
-			defer resp.Body.Close()
-			// 处理可能的压缩响应
-			var reader io.Reader
-			encoding := resp.Header.Get("Content-Encoding")
-			switch encoding {
-			case "gzip":
-				timeoutReader := &timeoutReader{r: resp.Body, timeout: 3 * time.Second}
-				gzr, err := gzip.NewReader(timeoutReader)
-				if err != nil {
-					company.Email = ""
-					mu.Lock()
-					tempWriter.Write([]string{company.Number, company.Name, company.Link2, ""})
-					tempWriter.Flush()
-					mu.Unlock()
-					fmt.Printf("%s,%s,%s,%s,,解压gzip失败: %v\n", company.Number, company.Name, company.Address, link, err)
-					return
-				}
-				defer gzr.Close()
-				reader = gzr
-			case "br":
-				timeoutReader := &timeoutReader{r: resp.Body, timeout: 3 * time.Second}
-				reader = brotli.NewReader(timeoutReader)
-			default:
-				reader = resp.Body
-			}
-			doc, err := goquery.NewDocumentFromReader(reader)
-			if err != nil {
-				company.Email = ""
-				mu.Lock()
-				tempWriter.Write([]string{company.Number, company.Name, company.Link2, ""})
-				tempWriter.Flush()
-				mu.Unlock()
-				fmt.Printf("%s,%s,%s,%s,,解析失败: %v\n", company.Number, company.Name, company.Address, link, err)
-				return
-			}
-			email := extractEmail(doc.Text())
-			company.Email = email
-			
-			// 清理邮箱格式
-			email = strings.ReplaceAll(email, "\n", "")
-			email = strings.ReplaceAll(email, "\r", "")
-			email = strings.TrimSpace(email)
-			
-			mu.Lock()
-			tempWriter.Write([]string{company.Number, company.Name, company.Link2, email})
-			tempWriter.Flush()
-			mu.Unlock()
-			
-			if email != "" {
-				if usedProxy {
-					fmt.Printf("%s,%s,%s,%s,%s,成功,切换代理访问成功\n", company.Number, company.Name, company.Address, link, email)
-				} else {
-					fmt.Printf("%s,%s,%s,%s,%s,成功\n", company.Number, company.Name, company.Address, link, email)
-				}
-			} else {
-				if resp.StatusCode == 200 {
-					if usedProxy {
-						fmt.Printf("%s,%s,%s,%s,,网站源代码并没有邮件信息，需要进一步处理...,切换代理访问成功\n", company.Number, company.Name, company.Address, link)
-					} else {
-						fmt.Printf("%s,%s,%s,%s,,网站源代码并没有邮件信息，需要进一步处理...\n", company.Number, company.Name, company.Address, link)
-					}
-				} else {
-					fmt.Printf("%s,%s,%s,%s,,状态码: %d\n", company.Number, company.Name, company.Address, link, resp.StatusCode)
-				}
-			}
-		}(i)
+// record 转换为数据库中的记录，邮箱都来自公司官网
+func record(input crawler.InputFile, c Company, email string) crawler.Company {
+	number, _ := strconv.Atoi(c.Number)
+	r := crawler.Company{
+		Category: input.Type,
+		Country:  input.Country,
+		Number:   number,
+		Name:     c.Name,
+		Address:  c.Address,
+		Link1:    c.Link1,
+		Link2:    c.Link2,
+		TLS:      c.TLS,
+		Outcome:  c.Outcome,
+		Pass:     c.Pass,
 	}
-	wg.Wait()
-	tempWriter.Flush()
-	tempOut.Close()
+	if email != "" {
+		r.Email, r.EmailSource = email, crawler.EmailFromWebsite
+	}
+	return r
+}
 
-	// 读取临时文件，排序后写入最终文件
-	tempData, err := os.Open(tempFile)
-	if err != nil {
-		log.Printf("无法打开临时文件：%v\n", err)
-		return
+func main() {
+	maxConcurrency := flag.Int("maxConcurrency", 100, "最大并发数（开启 -adaptive 时为上限）")
+	perDomain := flag.Int("perDomain", 0, "同一注册域名同时进行的最大请求数（如 2），0 表示不限制")
+	adaptive := flag.Bool("adaptive", false, "根据超时和429自动调整并发，-maxConcurrency 作为上限")
+	robots := flag.Bool("robots", false, "遵守robots.txt（Disallow和Crawl-delay），被禁止的URL记为跳过")
+	cacheDir := flag.String("cache", "", "响应缓存目录（如 cache），为空时不缓存")
+	cacheTTL := flag.Duration("cacheTTL", 7*24*time.Hour, "缓存有效期，过期后重新下载")
+	offline := flag.Bool("offline", false, "离线模式：只从缓存读取页面，不访问网络，需要 -cache")
+	retry := flag.Bool("retry", false, "超时或代理失败的记录在最后用更长超时、其他代理和链接变体再试一次")
+	grace := flag.Duration("grace", 10*time.Second, "收到Ctrl-C后等待进行中请求的时间")
+	tlsAllow := flag.String("tlsAllow", "tls_allow.txt", "证书白名单文件，每行一个host，这些host直接跳过证书校验")
+	fileType := flag.String("type", "installer", "只处理该类型的文件，逗号分隔，为空时不限")
+	countries := flag.String("country", "", "只处理这些国家，逗号分隔，如 Germany,Italy")
+	latest := flag.Bool("latest", false, "同一国家有多个日期的文件时只处理最新的")
+	outRoot := flag.String("outDir", "procedure1", "输出根目录，每次运行写入其中的 <时间>-<运行ID> 子目录，latest 指向最新一次")
+	dbPath := flag.String("db", crawler.DefaultStorePath, "结果同时写入的数据库，为空时不写")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "用法：%s [选项] [目录或文件或通配符...]（默认 Company 目录）\n", os.Args[0])
+		flag.PrintDefaults()
 	}
-	defer tempData.Close()
-	
-	csvReader := csv.NewReader(tempData)
-	records, err = csvReader.ReadAll()
-	if err != nil {
-		log.Printf("读取临时CSV失败：%v\n", err)
-		return
+	flag.Parse()
+
+	// 输入文件：目录、通配符（如 'Company/installer_*_Company*.csv'）或文件路径
+	patterns := flag.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"Company"}
 	}
-	
-	// 跳过表头进行排序
-	header := records[0]
-	dataRecords := records[1:]
-	
-	// 按Number排序
-	sort.Slice(dataRecords, func(i, j int) bool {
-		numI, errI := strconv.Atoi(dataRecords[i][0])
-		numJ, errJ := strconv.Atoi(dataRecords[j][0])
-		if errI == nil && errJ == nil {
-			return numI < numJ
-		}
-		return dataRecords[i][0] < dataRecords[j][0]
+	inputs, err := crawler.DiscoverInputs(patterns, crawler.InputFilter{
+		Types:     crawler.SplitList(*fileType),
+		Countries: crawler.SplitList(*countries),
+		Stage:     "Company",
+		Latest:    *latest,
 	})
-	
-	// 写入最终排序后的文件
-	outfile, err := os.Create(outputFile)
 	if err != nil {
-		log.Printf("无法创建输出CSV：%v\n", err)
-		return
+		log.Fatal(err)
 	}
-	defer outfile.Close()
-	writer := csv.NewWriter(outfile)
-	defer writer.Flush()
-	
-	// 写入表头
-	writer.Write(header)
-	
-	// 写入排序后的数据
-	for _, record := range dataRecords {
-		writer.Write(record)
+	if len(inputs) == 0 {
+		log.Fatal("没有符合条件的输入文件")
 	}
-	writer.Flush()
-	
-	// 删除临时文件
-	os.Remove(tempFile)
-
-	fmt.Printf("邮箱提取完成，结果已保存到 %s\n", outputFile)
-	fmt.Printf("总耗时：%v\n", time.Since(start))
 
-	// 统计输出
-	totalCount := len(dataRecords)
-	failCount := 0
-	for _, record := range dataRecords {
-		if len(record) > 3 && record[3] == "" {
-			failCount++
+	// 参数和配置都检查完再创建运行目录，否则出错时 latest 会指向一个空目录
+	policy, err := crawler.LoadTLSPolicy(*tlsAllow)
+	if err != nil {
+		log.Fatal(err)
+	}
+	cfg := crawler.DefaultFetchConfig()
+	cfg.TLS = policy
+	cfg.Robots = *robots
+	if *adaptive {
+		cfg.Limiter = crawler.NewAdaptiveLimiter(min(10, *maxConcurrency), *maxConcurrency)
+	}
+	cfg.CacheTTL = *cacheTTL
+	cfg.Offline = *offline
+	if *cacheDir != "" {
+		cache, err := crawler.OpenCache(*cacheDir)
+		if err != nil {
+			log.Fatal(err)
 		}
+		cfg.Cache = cache
+	} else if *offline {
+		log.Fatal("离线模式需要指定 -cache 目录")
 	}
-	failRate := 0.0
-	if totalCount > 0 {
-		failRate = float64(failCount) / float64(totalCount) * 100
+	fetcher := crawler.NewFetcher(cfg)
+	var retryFetcher *crawler.Fetcher
+	if *retry {
+		retryFetcher = crawler.NewFetcher(crawler.RetryConfig(cfg))
 	}
-	fmt.Printf("总记录数：%d，失败数：%d，失败率：%.2f%%\n", totalCount, failCount, failRate)
-}
 
-func main() {
-	maxConcurrency := flag.Int("maxConcurrency", 100, "最大并发数")
-	flag.Parse()
+	var store *crawler.Store
+	if *dbPath != "" {
+		if store, err = crawler.OpenStore(*dbPath); err != nil {
+			log.Fatal(err)
+		}
+		defer store.Close()
+	}
 
-	inputFiles := []string{
-		// "Company/installer_Spain_Company20250507.csv",
-		"Company/installer_Germany_Company20250507.csv",
-		// "Company/installer_Italy_Company20250508.csv",
-		// "Company/installer_Netherlands_Company20250508.csv",
-		"Company/installer_United%20Kingdom_Company20250508.csv",
+	run, err := crawler.NewRun(*outRoot)
+	if err != nil {
+		log.Fatal(err)
 	}
+	run.SetFlags(flag.CommandLine)
+	var inputPaths []string
+	for _, input := range inputs {
+		inputPaths = append(inputPaths, input.Path)
+	}
+	if err := run.SetInputs(inputPaths...); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("本次运行输出目录：%s\n", run.Dir)
 
-	for _, inputFile := range inputFiles {
-		fmt.Printf("\n==== 开始处理文件：%s ===="+"\n", inputFile)
-		processFile(inputFile, *maxConcurrency)
+	// Ctrl-C 后不再读取新行，进行中的请求最多再等 grace
+	shutdown := crawler.NotifyShutdown(*grace)
+	defer shutdown.Stop()
+
+	// 所有文件同时处理，共用 maxConcurrency 个worker，各文件轮流取得worker；
+	// 每个文件仍单独输出结果和统计
+	pool := newScheduler(*maxConcurrency, *perDomain)
+	var wg sync.WaitGroup
+	for _, input := range inputs {
+		wg.Add(1)
+		go func(input crawler.InputFile) {
+			defer wg.Done()
+			fmt.Printf("\n==== 开始处理文件：%s ===="+"\n", input.Path)
+			processFile(input, run, store, pool, fetcher, retryFetcher, shutdown)
+		}(input)
+	}
+	wg.Wait()
+	pool.Wait()
+	if err := run.Finish(shutdown.Interrupted()); err != nil {
+		log.Println(err)
 	}
 }
@@ -2,20 +2,21 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"encoding/csv"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
-	"regexp"
-	"strings"
-	"sync"
+	"strconv"
 	"time"
 
-	"github.com/PuerkitoBio/goquery"
+	"go-crawler/crawler"
 )
 
-const MAX_CONCURRENCY = 1000 // 最大并发数，可根据需要修改
+const MAX_CONCURRENCY = 1000 // worker数量，可根据需要修改
 
 type Result struct {
 	Link1   string
@@ -30,151 +31,212 @@ type CsvRow struct {
 	Link1   string
 }
 
-// 增强邮箱提取，支持明文、mailto、let eee三种
-func extractEmailFromScript(html string) string {
-	// 1. 先找 let eee = 'xxx' 形式
-	reEEE := regexp.MustCompile(`let\s+eee\s*=\s*['\"]([^'\"]+)['\"]`)
-	matchEEE := reEEE.FindStringSubmatch(html)
-	if len(matchEEE) > 1 {
-		encoded := matchEEE[1]
-		email := strings.Replace(encoded, "#109#103#.cn", "@", 1)
-		email = strings.Replace(email, "#103#example123cn", ".com", 1)
-		if strings.Contains(email, "@") {
-			return email
-		}
-	}
-	// 2. 再找 mailto:xxx@xxx
-	reMailto := regexp.MustCompile(`mailto:([a-zA-Z0-9_.+-]+@[a-zA-Z0-9-]+\.[a-zA-Z0-9-.]+)`)
-	matchMailto := reMailto.FindStringSubmatch(html)
-	if len(matchMailto) > 1 {
-		return matchMailto[1]
-	}
-	// 3. 再找明文邮箱
-	rePlain := regexp.MustCompile(`[a-zA-Z0-9_.+-]+@[a-zA-Z0-9-]+\.[a-zA-Z0-9-.]+`)
-	matchPlain := rePlain.FindStringSubmatch(html)
-	if len(matchPlain) > 0 {
-		return matchPlain[0]
-	}
-	return ""
-}
-
-func fetchDetail(link1 string, client *http.Client) (string, string) {
-	url := link1
-	if !strings.HasPrefix(link1, "http") {
-		url = "https://www.enf.com.cn" + link1
-	}
-	req, _ := http.NewRequest("GET", url, nil)
+func fetchDetail(ctx context.Context, link1 string, client *http.Client) crawler.EnfProfile {
+	req, _ := http.NewRequestWithContext(ctx, "GET", crawler.ENFURL(link1), nil)
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) Chrome/124.0.0.0 Safari/537.36")
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", ""
+		return crawler.EnfProfile{}
 	}
 	defer resp.Body.Close()
 	body, _ := io.ReadAll(resp.Body)
-	html := string(body)
-	doc, _ := goquery.NewDocumentFromReader(strings.NewReader(html))
-	link2, _ := doc.Find(`a[itemprop=\"url\"]`).Attr("href")
-	email := extractEmailFromScript(html)
-	return link2, email
+	profile, _ := crawler.ParseProfile(body)
+	return profile
 }
 
 func main() {
-	// 用户直接指定要处理的文件
-	files := []string{
-		"procedure1/seller_India_Email20250508.csv",
-		// "procedure1/seller_United%20States_Email20250508.csv",
+	fileType := flag.String("type", "seller", "只处理该类型的文件，逗号分隔，为空时不限")
+	countries := flag.String("country", "", "只处理这些国家，逗号分隔，如 India,Brazil")
+	latest := flag.Bool("latest", false, "同一国家有多个日期的文件时只处理最新的")
+	outRoot := flag.String("outDir", "procedure1", "输出根目录，每次运行写入其中的 <时间>-<运行ID> 子目录")
+	inPlace := flag.Bool("inPlace", false, "把结果写回输入文件（原文件先备份为 <文件名>.<时间>.bak）")
+	dbPath := flag.String("db", crawler.DefaultStorePath, "结果同时写入的数据库，为空时不写")
+	flag.Parse()
+
+	// 所有worker共用一个client，复用连接
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			MaxIdleConns:        MAX_CONCURRENCY,
+			MaxIdleConnsPerHost: MAX_CONCURRENCY,
+			IdleConnTimeout:     30 * time.Second,
+		},
+	}
+
+	// Ctrl-C 后停止提交新任务，进行中的请求最多再等10秒
+	shutdown := crawler.NotifyShutdown(10 * time.Second)
+	defer shutdown.Stop()
+
+	// 输入文件：目录、通配符（如 'procedure1/seller_*_Email*.csv'）或文件路径，默认 procedure1 目录
+	patterns := flag.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"procedure1"}
+	}
+	inputs, err := crawler.DiscoverInputs(patterns, crawler.InputFilter{
+		Types:     crawler.SplitList(*fileType),
+		Countries: crawler.SplitList(*countries),
+		Latest:    *latest,
+	})
+	if err != nil {
+		fmt.Println(err)
+		return
 	}
-	for _, filename := range files {
+	if len(inputs) == 0 {
+		fmt.Println("没有符合条件的输入文件")
+		return
+	}
+	var store *crawler.Store
+	if *dbPath != "" {
+		if store, err = crawler.OpenStore(*dbPath); err != nil {
+			fmt.Println(err)
+			return
+		}
+		defer store.Close()
+	}
+	run, err := crawler.NewRun(*outRoot)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	run.SetFlags(flag.CommandLine)
+	var inputPaths []string
+	for _, input := range inputs {
+		inputPaths = append(inputPaths, input.Path)
+	}
+	if err := run.SetInputs(inputPaths...); err != nil {
+		fmt.Println(err)
+	}
+	defer func() {
+		if err := run.Finish(shutdown.Interrupted()); err != nil {
+			fmt.Println(err)
+		}
+	}()
+	for _, input := range inputs {
+		filename := input.Path
+		if shutdown.Interrupted() {
+			break
+		}
 		fmt.Printf("处理文件: %s\n", filename)
 		f, err := os.Open(filename)
 		if err != nil {
 			fmt.Printf("无法打开文件 %s: %v\n", filename, err)
 			continue
 		}
-		reader := csv.NewReader(bufio.NewReader(f))
-		head, err := reader.Read()
+		// seller 文件的 Website 列是ENF详情页，按 Link1 读取
+		reader, err := crawler.NewCSVReader(bufio.NewReader(f), crawler.FieldNumber, crawler.FieldName, crawler.FieldEmail, crawler.FieldLink1)
 		if err != nil {
-			fmt.Printf("读取头部失败: %v\n", err)
+			fmt.Printf("文件 %s %v\n", filename, err)
 			f.Close()
 			continue
 		}
-		idxNumber, idxCompany, idxEmail, idxWebsite := -1, -1, -1, -1
-		for i, h := range head {
-			switch strings.TrimSpace(h) {
-			case "Number":
-				idxNumber = i
-			case "Company Name":
-				idxCompany = i
-			case "Email":
-				idxEmail = i
-			case "Website":
-				idxWebsite = i
-			}
-		}
-		if idxNumber == -1 || idxCompany == -1 || idxEmail == -1 || idxWebsite == -1 {
-			fmt.Printf("文件 %s 缺少必要字段\n", filename)
-			f.Close()
-			continue
+		mapping := reader.Mapping
+		if report := mapping.Report(); report != "" {
+			fmt.Printf("文件 %s %s\n", filename, report)
 		}
+		head := mapping.Header
+		idxNumber := mapping.Index(crawler.FieldNumber)
+		idxCompany := mapping.Index(crawler.FieldName)
+		idxEmail := mapping.Index(crawler.FieldEmail)
 		var records [][]string
 		var needFetchIdx []int
 		var needFetchLinks []string
-		for idx := 0; ; idx++ {
-			record, err := reader.Read()
+		var readErr error
+		for {
+			c, record, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			var rowErr *crawler.RowError
+			if errors.As(err, &rowErr) {
+				// 无法转换的行原样写回，不抓取
+				fmt.Printf("文件 %s 跳过%v\n", filename, err)
+				records = append(records, record)
+				continue
+			}
 			if err != nil {
+				readErr = err
 				break
 			}
-			records = append(records, record)
-			if strings.TrimSpace(record[idxEmail]) == "" {
-				needFetchIdx = append(needFetchIdx, idx)
-				needFetchLinks = append(needFetchLinks, record[idxWebsite])
+			if mapping.Get(record, crawler.FieldEmail) == "" {
+				needFetchIdx = append(needFetchIdx, len(records))
+				needFetchLinks = append(needFetchLinks, c.Link1)
 			}
+			records = append(records, record)
 		}
 		f.Close()
+		if readErr != nil {
+			// 读到一半的文件不能写出，-inPlace 时会截断原文件
+			fmt.Printf("读取文件 %s 失败，跳过该文件: %v\n", filename, readErr)
+			continue
+		}
 
-		// 并发抓取邮箱
+		// 并发抓取邮箱：固定数量的worker，共用一个client
+		type fetchJob struct {
+			I     int
+			Idx   int
+			Link1 string
+		}
 		type fetchResult struct {
 			Idx   int
 			Email string
 		}
+		// 中断时没有提交的任务不在 results 中，写回时只看 done 为 true 的
 		results := make([]fetchResult, len(needFetchIdx))
-		wg := sync.WaitGroup{}
-		sem := make(chan struct{}, MAX_CONCURRENCY) // 用户可指定
+		done := make([]bool, len(needFetchIdx))
+		pool := crawler.NewPool(MAX_CONCURRENCY, func(job fetchJob) {
+			profile := fetchDetail(shutdown.Context(), job.Link1, client)
+			email := profile.Email
+			warn := ""
+			if profile.Throttled {
+				warn = " [警告: 并发限制邮箱]"
+			}
+			results[job.I] = fetchResult{Idx: job.Idx, Email: email}
+			done[job.I] = true
+			fmt.Printf("%s %s 填充邮箱: %s%s\n", records[job.Idx][idxNumber], records[job.Idx][idxCompany], email, warn)
+		})
 		for i, idx := range needFetchIdx {
-			wg.Add(1)
-			go func(i, idx int, link1 string) {
-				defer wg.Done()
-				sem <- struct{}{}
-				client := &http.Client{Timeout: 10 * time.Second}
-				_, email := fetchDetail(link1, client)
-				warn := ""
-				if email == "alan@enfsolar.com" {
-					email = ""
-					warn = " [警告: 并发限制邮箱]"
-				}
-				results[i] = fetchResult{Idx: idx, Email: email}
-				fmt.Printf("%s %s 填充邮箱: %s%s\n", records[idx][idxNumber], records[idx][idxCompany], email, warn)
-				<-sem
-			}(i, idx, needFetchLinks[i])
-		}
-		wg.Wait()
+			if shutdown.Interrupted() {
+				break // 不再提交新任务，已填充的部分照常写回
+			}
+			pool.Submit(fetchJob{I: i, Idx: idx, Link1: needFetchLinks[i]})
+		}
+		pool.Wait()
 
-		// 写回邮箱
-		for _, r := range results {
+		// 写回邮箱，没有抓到的保留原值
+		var found []crawler.Company
+		fetched, successCount := 0, 0
+		for i, r := range results {
+			if !done[i] {
+				continue
+			}
+			fetched++
+			if r.Email == "" {
+				continue
+			}
+			successCount++
 			records[r.Idx][idxEmail] = r.Email
+			number, _ := strconv.Atoi(records[r.Idx][idxNumber])
+			found = append(found, crawler.Company{
+				Category:    input.Type,
+				Country:     input.Country,
+				Number:      number,
+				Name:        records[r.Idx][idxCompany],
+				Link1:       mapping.Get(records[r.Idx], crawler.FieldLink1),
+				Email:       r.Email,
+				EmailSource: crawler.EmailFromProfile,
+			})
 		}
-
-		// 统计填充情况
-		successCount := 0
-		for _, r := range results {
-			if r.Email != "" {
-				successCount++
+		if store != nil {
+			if err := store.Save(found...); err != nil {
+				fmt.Println(err)
 			}
 		}
+
+		// 统计填充情况
 		remainCount := 0
 		totalCount := len(records)
 		for _, rec := range records {
-			if strings.TrimSpace(rec[idxEmail]) == "" {
+			if mapping.Get(rec, crawler.FieldEmail) == "" {
 				remainCount++
 			}
 		}
@@ -183,20 +245,55 @@ func main() {
 			remainPercent = float64(remainCount) * 100.0 / float64(totalCount)
 		}
 
-		// 重新写回原文件
-		out, err := os.Create(filename)
+		// 默认写入本次运行目录；-inPlace 时先备份再替换原文件。
+		// 都是先写临时文件、fsync 后改名，写到一半出错不会破坏原有文件。
+		// 中断时输出文件名标记为未完成，-inPlace 也不覆盖原文件
+		output := run.OutputPath(input, input.Stage)
+		interrupted := shutdown.Interrupted()
+		switch {
+		case interrupted && *inPlace:
+			output = crawler.IncompletePath(filename)
+		case interrupted:
+			output = crawler.IncompletePath(output)
+		case *inPlace:
+			backup, err := crawler.BackupFile(filename)
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			fmt.Printf("原文件已备份到 %s\n", backup)
+			output = filename
+		}
+		out, err := crawler.CreateAtomic(output)
 		if err != nil {
-			fmt.Printf("无法写入文件 %s: %v\n", filename, err)
+			fmt.Printf("无法写入文件 %s: %v\n", output, err)
 			continue
 		}
 		writer := csv.NewWriter(out)
 		writer.Write(head)
-		for _, r := range records {
-			writer.Write(r)
+		writer.WriteAll(records)
+		if err := writer.Error(); err != nil {
+			fmt.Printf("无法写入文件 %s: %v\n", output, err)
+			out.Close()
+			continue
+		}
+		if err := out.Commit(); err != nil {
+			fmt.Println(err)
+			continue
+		}
+		if err := run.AddFile(crawler.FileManifest{
+			Input:  filename,
+			Output: output,
+			Rows:   totalCount,
+			Failed: fetched - successCount,
+		}); err != nil {
+			fmt.Println(err)
+		}
+		if interrupted {
+			fmt.Printf("文件 %s 处理被中断，%d 条未抓取，已完成的部分保存到 %s\n", filename, len(results)-fetched, output)
+		} else {
+			fmt.Printf("文件 %s 处理完成，结果已保存到 %s\n", filename, output)
 		}
-		writer.Flush()
-		out.Close()
-		fmt.Printf("文件 %s 处理完成\n", filename)
 		fmt.Printf("本次成功填充了%d个，还剩%d个（%.2f%%）\n", successCount, remainCount, remainPercent)
 	}
 	fmt.Println("全部文件处理完成！")
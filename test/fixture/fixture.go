@@ -0,0 +1,132 @@
+// Package fixture test/ 下解析类检查脚本共用的 fixture 比对：fixture 目录中每个
+// <前缀><名称>.html 的解析结果与同名的 .json 比较，-update 时用解析结果覆盖 .json。
+// .json 由解析结果生成，只能说明结果没有变化；各脚本另外用 Checker 手写关键字段的期望值，
+// 这部分不随 -update 改变。
+package fixture
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// Dir 默认的 fixture 目录 test/fixtures。按本文件的位置定位，脚本在仓库内任意目录下都能运行。
+func Dir() string {
+	_, file, _, ok := runtime.Caller(0)
+	if !ok {
+		return filepath.Join("test", "fixtures")
+	}
+	return filepath.Join(filepath.Dir(file), "..", "fixtures")
+}
+
+// Flags 注册 -dir 和 -update，在 flag.Parse 之前调用
+func Flags() (dir *string, update *bool) {
+	dir = flag.String("dir", Dir(), "fixture 目录")
+	update = flag.Bool("update", false, "用当前的解析结果覆盖 .json（手写的检查仍然执行）")
+	return dir, update
+}
+
+// Checker 记录检查失败的项数
+type Checker struct {
+	Failed int
+}
+
+// Check ok 为 false 时打印失败信息
+func (c *Checker) Check(ok bool, format string, args ...any) {
+	if !ok {
+		fmt.Printf("失败："+format+"\n", args...)
+		c.Failed++
+	}
+}
+
+// Equal 检查 got 与手写的 want 相同
+func (c *Checker) Equal(label string, got, want any) {
+	c.Check(reflect.DeepEqual(got, want), "%s：期望 %#v，实际 %#v", label, want, got)
+}
+
+// Done 有失败时退出，否则打印"通过"
+func (c *Checker) Done() {
+	if c.Failed > 0 {
+		fmt.Printf("失败：%d 项检查没有通过\n", c.Failed)
+		os.Exit(1)
+	}
+	fmt.Println("通过")
+}
+
+// Golden 解析 dir 中每个 <prefix>*.html 并与同名 .json 比较，update 时覆盖 .json。
+// parse 的 name 为去掉前缀和扩展名的部分，返回的结果也以它为键，供脚本做手写的检查。
+func Golden[T any](c *Checker, dir, prefix string, update bool, parse func(name string, body []byte) (T, error)) map[string]T {
+	pages, err := filepath.Glob(filepath.Join(dir, prefix+"*.html"))
+	if err != nil || len(pages) == 0 {
+		fmt.Printf("失败：%s 中没有 %s*.html\n", dir, prefix)
+		os.Exit(1)
+	}
+	sort.Strings(pages)
+	results := make(map[string]T)
+	for _, page := range pages {
+		name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(page), prefix), ".html")
+		body, err := os.ReadFile(page)
+		if err != nil {
+			fmt.Printf("读取 %s 失败: %v\n", page, err)
+			os.Exit(1)
+		}
+		got, err := parse(name, body)
+		if err != nil {
+			c.Check(false, "%s: %v", filepath.Base(page), err)
+			continue
+		}
+		results[name] = got
+
+		expectedPath := strings.TrimSuffix(page, ".html") + ".json"
+		if update {
+			data, _ := json.MarshalIndent(got, "", "  ")
+			if err := os.WriteFile(expectedPath, append(data, '\n'), 0644); err != nil {
+				fmt.Printf("写入 %s 失败: %v\n", expectedPath, err)
+				os.Exit(1)
+			}
+			fmt.Printf("已更新：%s\n", expectedPath)
+			continue
+		}
+		data, err := os.ReadFile(expectedPath)
+		if err != nil {
+			fmt.Printf("读取 %s 失败: %v\n", expectedPath, err)
+			os.Exit(1)
+		}
+		var want T
+		if err := json.Unmarshal(data, &want); err != nil {
+			fmt.Printf("解析 %s 失败: %v\n", expectedPath, err)
+			os.Exit(1)
+		}
+		if diffs := compare(want, got); len(diffs) > 0 {
+			c.Check(false, "%s 与 %s 不同", filepath.Base(page), filepath.Base(expectedPath))
+			for _, d := range diffs {
+				fmt.Println("  " + d)
+			}
+		}
+	}
+	return results
+}
+
+// compare 结构体逐字段比较，其余类型整体比较，返回不同之处
+func compare(want, got any) []string {
+	w, g := reflect.ValueOf(want), reflect.ValueOf(got)
+	if w.Kind() != reflect.Struct {
+		if reflect.DeepEqual(want, got) {
+			return nil
+		}
+		return []string{fmt.Sprintf("期望 %+v\n  实际 %+v", want, got)}
+	}
+	var diffs []string
+	for i := 0; i < w.NumField(); i++ {
+		if !reflect.DeepEqual(w.Field(i).Interface(), g.Field(i).Interface()) {
+			diffs = append(diffs, fmt.Sprintf("%s：期望 %#v，实际 %#v", w.Type().Field(i).Name, w.Field(i).Interface(), g.Field(i).Interface()))
+		}
+	}
+	return diffs
+}
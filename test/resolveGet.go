@@ -1,7 +1,6 @@
 package main
 
 import (
-	"crypto/tls"
 	"fmt"
 	"io"
 	"net"
@@ -16,6 +15,7 @@ import (
 	// brotli解码库
 	"github.com/andybalholm/brotli"
 	"golang.org/x/net/proxy"
+	"go-crawler/crawler"
 )
 
 // 代理账号信息
@@ -47,6 +47,15 @@ func main() {
 func tryWithProxy(targetURL string) error {
 	fmt.Println("尝试使用SOCKS5代理...")
 	
+	tlsPolicy, err := crawler.LoadTLSPolicy("tls_allow.txt")
+	if err != nil {
+		return err
+	}
+	host := ""
+	if u, err := url.Parse(targetURL); err == nil {
+		host = u.Hostname()
+	}
+
 	// 尝试所有代理
 	for i, account := range proxyAccounts {
 		proxyURL := fmt.Sprintf("socks5://%s:%s@p.webshare.io:80", account.Username, account.Password)
@@ -63,9 +72,8 @@ func tryWithProxy(targetURL string) error {
 			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
 				return dialer.Dial(network, addr)
 			},
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: true,
-			},
+			// 默认校验证书，只有 tls_allow.txt 中的host跳过
+			TLSClientConfig: tlsPolicy.Config(host),
 		}
 		
 		client := &http.Client{
@@ -93,6 +101,9 @@ func tryWithProxy(targetURL string) error {
 		resp, err := client.Do(req)
 		if err != nil {
 			fmt.Printf("  代理 %d 请求失败: %v\n", i+1, err)
+			if class := crawler.ClassifyTLSError(err); class != "" {
+				fmt.Printf("  TLS错误类别: %s（如确认可信，可加入 tls_allow.txt）\n", class)
+			}
 			continue
 		}
 		
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"go-crawler/crawler"
+	"go-crawler/test/fixture"
+)
+
+// 用保存的目录列表页检查各目录的解析：test/fixtures 中每个 listing_<目录>.html
+// 按该目录的解析方式解析（国家为 Germany），结果与同名的 .json 比较，
+// 检查特有的列都在目录的列定义中，并按页面内容手写检查公司数和各列的值。
+// 确认解析结果无误后可用 -update 重新生成 .json。
+// 运行：go run test/testCategories.go（在仓库内任意目录下均可）
+func main() {
+	dir, update := fixture.Flags()
+	flag.Parse()
+
+	c := &fixture.Checker{}
+	got := fixture.Golden(c, *dir, "listing_", *update, func(name string, body []byte) ([]crawler.ListingEntry, error) {
+		cat, err := crawler.LookupCategory(name)
+		if err != nil {
+			return nil, err
+		}
+		entries, err := cat.Parse(body, cat, "Germany")
+		if err != nil {
+			return nil, err
+		}
+		columns := make(map[string]bool)
+		for _, col := range cat.ColumnNames() {
+			columns[col] = true
+		}
+		for _, e := range entries {
+			for col := range e.Details {
+				c.Check(columns[col], "listing_%s 的 %s 有未定义的列 %s", name, e.Name, col)
+			}
+		}
+		fmt.Printf("%s（%s）：%d 家公司，列 %s\n", cat.Name, cat.CustomerType, len(entries),
+			strings.Join(append([]string{crawler.FieldName, crawler.FieldAddress}, cat.ColumnNames()...), ", "))
+		return entries, nil
+	})
+
+	// 每个目录的公司数，广告行和筛选表格不算
+	for name, want := range map[string]int{"installer": 2, "panel": 2, "storage": 2} {
+		c.Equal("listing_"+name+" 公司数", len(got[name]), want)
+	}
+	if installer := got["installer"]; len(installer) == 2 {
+		c.Equal("installer[0].Name", installer[0].Name, "Sonnenkraft Energietechnik GmbH")
+		c.Equal("installer[0].Link1", installer[0].Link1, crawler.ENFBaseURL+"/sonnenkraft-energietechnik?directory=installer&utm_content=150001")
+		c.Equal("installer[0].Address", installer[0].Address, "Bayern")
+		c.Equal("installer[1].Link1", installer[1].Link1, "https://www.enf.com.cn/nordlicht-solar?directory=installer&utm_content=150002")
+		c.Equal("installer[1].Address", installer[1].Address, "Hamburg")
+	}
+	if panel := got["panel"]; len(panel) == 2 {
+		c.Equal("panel[0].Address", panel[0].Address, "Köln, Germany")
+		c.Equal("panel[0].Details", panel[0].Details, map[string]string{"Panel Type": "Monocrystalline, Bifacial", "Power Range": "400 - 550 W"})
+		c.Equal("panel[1].Details", panel[1].Details, map[string]string{"Panel Type": "Polycrystalline, Thin film", "Power Range": "280 - 330 W"})
+	}
+	if storage := got["storage"]; len(storage) == 2 {
+		c.Equal("storage[0].Name", storage[0].Name, "Speicherwerk GmbH")
+		c.Equal("storage[0].Address", storage[0].Address, "Stuttgart")
+		c.Equal("storage[0].Details", storage[0].Details, map[string]string{"Battery Type": "磷酸铁锂", "Capacity": "5 - 20 kWh", "Application": "住宅, 商业"})
+		c.Equal("storage[1].Details", storage[1].Details, map[string]string{"Battery Type": "钠离子", "Application": "工商业"})
+	}
+	c.Done()
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"go-crawler/crawler"
+	"go-crawler/test/fixture"
+)
+
+// 检查国家目录：test/fixtures 中每个 index_<目录>.html（目录首页）的解析结果与同名的 .json 比较，
+// 并按页面内容手写检查国家名、ISO代码、中文名和公司数；再检查按ISO代码、中文名、URL编码的名字
+// 都能查到国家，输出文件名中的国家名不再是 United%20States，旧文件名仍能解析。
+// 运行：go run test/testCountries.go（在仓库内任意目录下均可）
+func main() {
+	dir, update := fixture.Flags()
+	flag.Parse()
+
+	c := &fixture.Checker{}
+	catalog := &crawler.Catalog{}
+	got := fixture.Golden(c, *dir, "index_", *update, func(name string, body []byte) ([]crawler.Country, error) {
+		cat, err := crawler.LookupCategory(name)
+		if err != nil {
+			return nil, err
+		}
+		countries, err := crawler.ParseDirectoryIndex(body, cat)
+		if err != nil {
+			return nil, err
+		}
+		catalog.Merge(cat.Name, countries)
+		fmt.Printf("index_%s：%d 个国家\n", name, len(countries))
+		return countries, nil
+	})
+
+	// 导航中重复的 Germany、地区子页面不算；Atlantis 不认识，没有ISO代码
+	want := []struct {
+		name, iso, local string
+		count            int
+	}{
+		{"Germany", "DE", "德国", 6990},
+		{"Czech Republic", "CZ", "捷克共和国", 312},
+		{"United Kingdom", "GB", "英国", 4107},
+		{"United States", "US", "美国", 8451},
+		{"Atlantis", "", "", 3},
+	}
+	installer := got["installer"]
+	c.Equal("index_installer 国家数", len(installer), len(want))
+	for i, w := range want {
+		if i >= len(installer) {
+			break
+		}
+		country := installer[i]
+		c.Equal(fmt.Sprintf("第 %d 个国家", i+1), country.Name, w.name)
+		c.Equal(w.name+" ISO", country.ISO, w.iso)
+		c.Equal(w.name+" 中文名", country.Local, w.local)
+		c.Equal(w.name+" installer 公司数", country.Counts["installer"], w.count)
+	}
+
+	for _, name := range []string{"United States", "united states", "US", "美国", "United%20States"} {
+		country, ok := catalog.Lookup(name)
+		c.Check(ok && country.Name == "United States", "按 %q 没有查到 United States（%+v）", name, country)
+	}
+	_, ok := catalog.Lookup("Narnia")
+	c.Check(!ok, "不存在的国家也查到了")
+	resolved, ok := crawler.ResolveCountry(nil, "Czech%20Republic")
+	c.Check(resolved == "Czech Republic" && !ok, "没有国家目录时 Czech%%20Republic 应该解码为 Czech Republic，实际 %q", resolved)
+
+	name := crawler.OutputName("installer", "United%20Kingdom", "Pipeline", "20250508", "csv")
+	c.Equal("输出文件名", name, "installer_United Kingdom_Pipeline20250508.csv")
+	for _, file := range []string{name, "installer_United%20Kingdom_Pipeline20250508.csv"} {
+		in, ok := crawler.ParseInputName(file)
+		c.Check(ok && in.Country == "United Kingdom" && in.Stage == "Pipeline", "无法解析 %s（%+v）", file, in)
+	}
+	c.Done()
+}
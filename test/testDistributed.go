@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go-crawler/crawler"
+)
+
+// 在本机上跑一遍分布式模式：模拟的ENF和公司官网、一个协调器、两个worker，
+// 外加一个领取任务后就"死掉"的worker，检查它的租约过期后任务被重新分配。
+// 编号不连续（模拟输入中跳过的行），检查结果仍按顺序写出；另外检查令牌和关闭后提交的结果被拒绝，
+// 以及重试任务还在worker手里时中断，记录按第一轮结果写出。
+// 运行：go run test/testDistributed.go
+func main() {
+	const companies = 30
+	const token = "secret"
+
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "<html>Contact: sales@%s</html>", strings.Trim(r.URL.Path, "/"))
+	}))
+	defer site.Close()
+	enf := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/company/") {
+			id := strings.TrimPrefix(r.URL.Path, "/company/")
+			fmt.Fprintf(w, `<a itemprop="url" href="%s/c%s.com">官网</a>`, site.URL, id)
+			return
+		}
+		fmt.Fprintf(w, "<html>未知页面</html>")
+	}))
+	defer enf.Close()
+	crawler.ENFBaseURL = enf.URL
+
+	out, err := os.CreateTemp("", "distributed_*.csv")
+	if err != nil {
+		fmt.Printf("创建临时文件失败: %v\n", err)
+		return
+	}
+	out.Close()
+	defer os.Remove(out.Name())
+	sink, err := crawler.NewCSVSink(out.Name())
+	if err != nil {
+		fmt.Printf("创建输出失败: %v\n", err)
+		return
+	}
+
+	co := crawler.NewCoordinator(sink)
+	co.LeaseTTL = time.Second
+	co.MaxBatch = 5
+	co.Token = token
+	server := httptest.NewServer(co)
+	defer server.Close()
+	go co.Reap(100 * time.Millisecond)
+
+	for i := 1; i <= companies; i++ {
+		co.Add(crawler.Company{
+			Category: "installer",
+			Country:  "Testland",
+			Number:   i*2 + 5,
+			Name:     fmt.Sprintf("Company %d", i),
+			Link1:    fmt.Sprintf("%s/company/%d", enf.URL, i),
+		})
+	}
+	co.CloseInput()
+
+	post := func(path, auth string, v any) (*http.Response, error) {
+		body, _ := json.Marshal(v)
+		req, _ := http.NewRequest(http.MethodPost, server.URL+path, bytes.NewReader(body))
+		if auth != "" {
+			req.Header.Set("Authorization", "Bearer "+auth)
+		}
+		return http.DefaultClient.Do(req)
+	}
+	resp, err := post(crawler.PathLease, "wrong", crawler.LeaseRequest{Worker: "intruder", Max: 5})
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		fmt.Printf("失败：令牌错误时应返回401（%v）\n", err)
+		os.Exit(1)
+	}
+	resp.Body.Close()
+
+	// 领取一批任务后不续租也不提交，模拟worker宕机
+	resp, err = post(crawler.PathLease, token, crawler.LeaseRequest{Worker: "dead", Max: 5})
+	if err != nil || resp.StatusCode != http.StatusOK {
+		fmt.Printf("模拟worker领取任务失败: %v\n", err)
+		return
+	}
+	var dead crawler.Lease
+	json.NewDecoder(resp.Body).Decode(&dead)
+	resp.Body.Close()
+
+	cfg := crawler.DefaultFetchConfig()
+	cfg.UseProxy = false
+	enfCfg := crawler.DefaultENFConfig()
+	enfCfg.UseProxy = false
+	var wg sync.WaitGroup
+	for _, id := range []string{"worker-1", "worker-2"} {
+		p := &crawler.Pipeline{
+			ENF:     crawler.NewFetcher(enfCfg),
+			Website: crawler.NewFetcher(cfg),
+		}
+		w := crawler.NewWorker(server.URL, id, p)
+		w.Token = token
+		w.Workers = 3
+		w.Batch = 5
+		w.Heartbeat = 300 * time.Millisecond
+		w.Poll = 100 * time.Millisecond
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := w.Run(context.Background()); err != nil {
+				fmt.Printf("%s 退出: %v\n", id, err)
+			}
+		}()
+	}
+
+	select {
+	case <-co.Done():
+	case <-time.After(30 * time.Second):
+		fmt.Println("失败：30秒内没有完成")
+		os.Exit(1)
+	}
+	wg.Wait()
+	count, err := co.Close()
+	if err != nil {
+		fmt.Printf("写出结果失败: %v\n", err)
+	}
+	// 关闭后迟到的结果不能再写入已关闭的输出
+	resp, err = post(crawler.PathComplete, token, crawler.LeaseResult{ID: dead.ID, Results: dead.Jobs})
+	if err != nil || resp.StatusCode != http.StatusGone {
+		fmt.Printf("失败：关闭后提交结果应返回410（%v）\n", err)
+		os.Exit(1)
+	}
+	resp.Body.Close()
+
+	f, err := os.Open(out.Name())
+	if err != nil {
+		fmt.Printf("读取结果失败: %v\n", err)
+		return
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		fmt.Printf("读取结果失败: %v\n", err)
+		return
+	}
+	seen := map[string]int{}
+	withEmail := 0
+	for i, record := range records[1:] {
+		if want := fmt.Sprint((i+1)*2 + 5); record[0] != want {
+			fmt.Printf("失败：第 %d 行编号为 %s，应为 %s\n", i+1, record[0], want)
+			os.Exit(1)
+		}
+		seen[record[0]]++
+		if strings.HasPrefix(record[6], "sales@") {
+			withEmail++
+		}
+	}
+	fmt.Printf("写出 %d 条，不同编号 %d 个，找到邮箱 %d 条\n", count, len(seen), withEmail)
+	for number, n := range seen {
+		if n > 1 {
+			fmt.Printf("失败：编号 %s 写出了 %d 次\n", number, n)
+			os.Exit(1)
+		}
+	}
+	if len(seen) != companies || withEmail != companies {
+		fmt.Println("失败：结果不完整")
+		os.Exit(1)
+	}
+	if err := interruptRetry(); err != nil {
+		fmt.Printf("失败：%v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("通过")
+}
+
+// interruptRetry 第一轮结果进入重试队列、重试任务被领走后还没提交就关闭协调器，
+// 两条记录都要写出，重试的那条保留第一轮的结果
+func interruptRetry() error {
+	out, err := os.CreateTemp("", "distributed_retry_*.csv")
+	if err != nil {
+		return fmt.Errorf("创建临时文件失败: %v", err)
+	}
+	out.Close()
+	defer os.Remove(out.Name())
+	sink, err := crawler.NewCSVSink(out.Name())
+	if err != nil {
+		return fmt.Errorf("创建输出失败: %v", err)
+	}
+	co := crawler.NewCoordinator(sink)
+	server := httptest.NewServer(co)
+	defer server.Close()
+	co.Add(crawler.Company{Number: 1, Name: "Company 1"})
+	co.Add(crawler.Company{Number: 2, Name: "Company 2"})
+	co.CloseInput()
+
+	post := func(path string, v, reply any) (int, error) {
+		body, _ := json.Marshal(v)
+		resp, err := http.Post(server.URL+path, "application/json", bytes.NewReader(body))
+		if err != nil {
+			return 0, err
+		}
+		defer resp.Body.Close()
+		if reply != nil && resp.StatusCode == http.StatusOK {
+			err = json.NewDecoder(resp.Body).Decode(reply)
+		}
+		return resp.StatusCode, err
+	}
+	var first crawler.Lease
+	if _, err := post(crawler.PathLease, crawler.LeaseRequest{Worker: "w", Max: 2}, &first); err != nil || len(first.Jobs) != 2 {
+		return fmt.Errorf("领取第一轮任务失败（%v）", err)
+	}
+	first.Jobs[0].Outcome = crawler.OutcomeFetchFailed
+	first.Jobs[1].Outcome = crawler.OutcomeOK
+	first.Jobs[1].Email = "sales@c2.com"
+	if status, err := post(crawler.PathComplete, crawler.LeaseResult{ID: first.ID, Results: first.Jobs}, nil); err != nil || status != http.StatusNoContent {
+		return fmt.Errorf("提交第一轮结果失败（%d，%v）", status, err)
+	}
+	var retry crawler.Lease
+	if _, err := post(crawler.PathLease, crawler.LeaseRequest{Worker: "w", Max: 2}, &retry); err != nil || len(retry.Jobs) != 1 {
+		return fmt.Errorf("领取重试任务失败（%v）", err)
+	}
+	count, err := co.Close()
+	if err != nil {
+		return fmt.Errorf("写出结果失败: %v", err)
+	}
+	if count != 2 {
+		return fmt.Errorf("重试任务租出时中断，写出 %d 条，应为 2 条", count)
+	}
+	if queued, leased := co.Stats(); queued != 0 || leased != 0 {
+		return fmt.Errorf("关闭后仍有 %d 条排队、%d 条租出", queued, leased)
+	}
+
+	f, err := os.Open(out.Name())
+	if err != nil {
+		return fmt.Errorf("读取结果失败: %v", err)
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return fmt.Errorf("读取结果失败: %v", err)
+	}
+	outcome := -1
+	for i, name := range records[0] {
+		if name == crawler.FieldOutcome {
+			outcome = i
+		}
+	}
+	if len(records) != 3 || outcome < 0 {
+		return fmt.Errorf("中断后的输出有 %d 行，应为表头加 2 行", len(records))
+	}
+	if records[1][0] != "2" || records[2][0] != "1" || records[2][outcome] != crawler.OutcomeFetchFailed {
+		return fmt.Errorf("中断后的输出不对：%v", records[1:])
+	}
+	return nil
+}
@@ -4,10 +4,11 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"crypto/tls"
 	"regexp"
 	"strings"
 	"time"
+
+	"go-crawler/crawler"
 )
 
 func main() {
@@ -15,6 +16,13 @@ func main() {
 	url := "https://www.x-elio.com"
 	fmt.Printf("开始测试直接访问 %s\n", url)
 
+	// 默认校验证书，只有 tls_allow.txt 中的host跳过
+	tlsPolicy, err := crawler.LoadTLSPolicy("tls_allow.txt")
+	if err != nil {
+		fmt.Printf("读取证书白名单失败: %v\n", err)
+		return
+	}
+
 	// 创建请求
 	client := &http.Client{
 		Timeout: 15 * time.Second,
@@ -22,7 +30,7 @@ func main() {
 			return http.ErrUseLastResponse
 		},
 		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			TLSClientConfig: tlsPolicy.Config("www.x-elio.com"),
 		},
 	}
 	req, err := http.NewRequest("GET", url, nil)
@@ -40,6 +48,9 @@ func main() {
 	resp, err := client.Do(req)
 	if err != nil {
 		fmt.Printf("请求失败: %v\n", err)
+		if class := crawler.ClassifyTLSError(err); class != "" {
+			fmt.Printf("TLS错误类别: %s\n", class)
+		}
 		return
 	}
 	defer resp.Body.Close()
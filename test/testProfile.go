@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"go-crawler/crawler"
+	"go-crawler/test/fixture"
+)
+
+// 用保存的ENF详情页检查 crawler.ParseProfile：test/fixtures 中每个 profile_<名称>.html
+// 解析的结果与同名的 .json 逐字段比较，另外按页面内容手写检查关键字段。
+// 页面改版后把新页面存为 fixture，确认解析结果无误后用 -update 重新生成 .json。
+// 运行：go run test/testProfile.go（在仓库内任意目录下均可）
+func main() {
+	dir, update := fixture.Flags()
+	flag.Parse()
+
+	c := &fixture.Checker{}
+	got := fixture.Golden(c, *dir, "profile_", *update, func(_ string, body []byte) (crawler.EnfProfile, error) {
+		return crawler.ParseProfile(body)
+	})
+
+	zh := got["installer_zh"]
+	c.Equal("installer_zh Name", zh.Name, "Sonnenkraft Energietechnik GmbH")
+	c.Equal("installer_zh Website", zh.Website, "https://www.sonnenkraft-energie.de")
+	c.Equal("installer_zh Email", zh.Email, "vertrieb@sonnenkraft-energie.de")
+	c.Equal("installer_zh Phone", zh.Phone, "+49 941 5566 770")
+	c.Equal("installer_zh City", zh.City, "Regensburg")
+	c.Equal("installer_zh Postcode", zh.Postcode, "93047")
+	c.Equal("installer_zh InstallationSizes", zh.InstallationSizes, []string{"住宅", "商业", "工业"})
+	c.Equal("installer_zh ServiceArea", zh.ServiceArea, []string{"Bayern", "Baden-Württemberg", "Österreich"})
+	c.Equal("installer_zh Brands", zh.Brands, []string{"JA Solar", "Trina Solar", "SMA", "Fronius"})
+	c.Equal("installer_zh Languages", zh.Languages, []string{"德语", "英语"})
+	c.Equal("installer_zh Extra", zh.Extra["成立时间"], "2009")
+
+	en := got["seller_en"]
+	c.Equal("seller_en Name", en.Name, "SolarPoint Distribution Ltd")
+	c.Equal("seller_en Email", en.Email, "sales@solarpoint-distribution.co.uk")
+	c.Equal("seller_en Address", en.Address, "Unit 4, Riverside Business Park, Leeds LS10 1AB, United Kingdom")
+	c.Equal("seller_en Products", en.Products, []string{"Solar Panels", "Inverters", "Storage Systems", "Mounting Systems"})
+	// 两个品牌项中重复的 LONGi 只保留一次
+	c.Equal("seller_en Brands", en.Brands, []string{"LONGi", "Canadian Solar", "REC", "GivEnergy", "SolarEdge"})
+	c.Equal("seller_en ServiceArea", en.ServiceArea, []string{"England", "Wales", "Scotland"})
+	c.Equal("seller_en Extra", en.Extra["Business Hours"], "Mon - Fri 08:30 - 17:30")
+
+	// 并发限制的占位邮箱不能当作公司邮箱
+	throttled := got["throttled"]
+	c.Equal("throttled Throttled", throttled.Throttled, true)
+	c.Equal("throttled Email", throttled.Email, "")
+	c.Equal("throttled Website", throttled.Website, "https://helio-instalaciones.es")
+	c.Equal("throttled Address", throttled.Address, "Calle Mayor 5, 28013 Madrid, 西班牙")
+
+	for _, name := range []string{"installer_zh", "seller_en", "throttled"} {
+		p := got[name]
+		fmt.Printf("%s：%s，%d 个品牌，%d 个服务区域\n", name, p.Name, len(p.Brands), len(p.ServiceArea))
+	}
+	c.Done()
+}